@@ -0,0 +1,60 @@
+package config
+
+import "fmt"
+
+// bundleMigrations keep each bundle's stock quantity equal to the number of
+// whole bundles its components can make. Triggers recompute it whenever a
+// component's stock or availability changes, whichever path changed it.
+var bundleMigrations = []string{
+	`CREATE OR REPLACE FUNCTION bundle_stock(bundle uuid) RETURNS numeric AS $$
+		SELECT COALESCE(MIN(FLOOR(
+			CASE
+				WHEN NOT p.is_active THEN 0
+				WHEN bc.component_variant_id IS NULL THEN p.stock_quantity
+				WHEN v.is_active THEN v.stock_quantity
+				ELSE 0
+			END / bc.quantity
+		)), 0)
+		FROM bundle_components bc
+		JOIN products p ON p.id = bc.component_product_id
+		LEFT JOIN product_variants v ON v.id = bc.component_variant_id
+		WHERE bc.bundle_id = bundle
+	$$ LANGUAGE sql STABLE`,
+	`CREATE OR REPLACE FUNCTION products_bundle_stock_update() RETURNS trigger AS $$
+	BEGIN
+		UPDATE products SET stock_quantity = bundle_stock(products.id)
+		WHERE products.is_bundle AND products.id IN (
+			SELECT bundle_id FROM bundle_components
+			WHERE component_product_id = NEW.id AND component_variant_id IS NULL
+		);
+		RETURN NULL;
+	END
+	$$ LANGUAGE plpgsql`,
+	`CREATE OR REPLACE FUNCTION variants_bundle_stock_update() RETURNS trigger AS $$
+	BEGIN
+		UPDATE products SET stock_quantity = bundle_stock(products.id)
+		WHERE products.is_bundle AND products.id IN (
+			SELECT bundle_id FROM bundle_components WHERE component_variant_id = NEW.id
+		);
+		RETURN NULL;
+	END
+	$$ LANGUAGE plpgsql`,
+	`DROP TRIGGER IF EXISTS products_bundle_stock_trigger ON products`,
+	// Bundles cannot contain bundles, so skipping them stops the trigger recursing
+	`CREATE TRIGGER products_bundle_stock_trigger
+		AFTER UPDATE OF stock_quantity, is_active ON products
+		FOR EACH ROW WHEN (NOT NEW.is_bundle) EXECUTE FUNCTION products_bundle_stock_update()`,
+	`DROP TRIGGER IF EXISTS variants_bundle_stock_trigger ON product_variants`,
+	`CREATE TRIGGER variants_bundle_stock_trigger
+		AFTER UPDATE OF stock_quantity, is_active ON product_variants
+		FOR EACH ROW EXECUTE FUNCTION variants_bundle_stock_update()`,
+}
+
+func migrateBundles() error {
+	for _, statement := range bundleMigrations {
+		if err := DB.Exec(statement).Error; err != nil {
+			return fmt.Errorf("failed to set up bundle stock: %w", err)
+		}
+	}
+	return nil
+}
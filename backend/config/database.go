@@ -29,12 +29,26 @@ func InitDB() {
 
 	log.Println("Database connected successfully")
 
+	backfillEmailVerified := needsEmailVerificationBackfill()
+
 	// Auto migrate the schema
 	err = DB.AutoMigrate(
+		&models.Role{},
 		&models.User{},
 		&models.Address{},
 		&models.Category{},
+		&models.Brand{},
 		&models.Product{},
+		&models.ProductVariant{},
+		&models.BundleComponent{},
+		&models.AttributeDefinition{},
+		&models.ProductAttributeValue{},
+		&models.PriceTier{},
+		&models.PriceChange{},
+		&models.ProductImportJob{},
+		&models.Review{},
+		&models.RelatedProduct{},
+		&models.RelatedProductOverride{},
 		&models.Cart{},
 		&models.CartItem{},
 		&models.Wishlist{},
@@ -43,12 +57,31 @@ func InitDB() {
 		&models.Payment{},
 		&models.Notification{},
 		&models.ServiceRequest{},
+		&models.Session{},
+		&models.RefreshToken{},
+		&models.RecoveryCode{},
+		&models.VerificationCode{},
+		&models.APIKey{},
 	)
 
 	if err != nil {
 		log.Fatal("Failed to migrate database:", err)
 	}
 
+	if err := migrateSearch(); err != nil {
+		log.Fatal(err)
+	}
+
+	if err := migrateBundles(); err != nil {
+		log.Fatal(err)
+	}
+
+	if backfillEmailVerified {
+		if err := backfillEmailVerification(); err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	log.Println("Database migrated successfully")
 }
 
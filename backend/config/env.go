@@ -27,6 +27,59 @@ func LoadEnv() {
 	}
 
 	if os.Getenv("JWT_EXPIRY") == "" {
-		os.Setenv("JWT_EXPIRY", "24h")
+		os.Setenv("JWT_EXPIRY", "15m")
+	}
+
+	if os.Getenv("REFRESH_TOKEN_EXPIRY") == "" {
+		os.Setenv("REFRESH_TOKEN_EXPIRY", "720h")
+	}
+
+	if os.Getenv("REQUIRE_ADMIN_2FA") == "" {
+		os.Setenv("REQUIRE_ADMIN_2FA", "false")
+	}
+
+	if os.Getenv("TOTP_ISSUER") == "" {
+		os.Setenv("TOTP_ISSUER", "Hardware Store")
+	}
+
+	if os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "" {
+		os.Setenv("REQUIRE_EMAIL_VERIFICATION", "false")
+	}
+
+	if os.Getenv("REQUIRE_PHONE_VERIFICATION") == "" {
+		os.Setenv("REQUIRE_PHONE_VERIFICATION", "false")
+	}
+
+	if os.Getenv("LOGIN_MAX_ATTEMPTS") == "" {
+		os.Setenv("LOGIN_MAX_ATTEMPTS", "5")
+	}
+
+	if os.Getenv("LOGIN_ATTEMPT_WINDOW") == "" {
+		os.Setenv("LOGIN_ATTEMPT_WINDOW", "15m")
+	}
+
+	if os.Getenv("LOGIN_LOCKOUT_DURATION") == "" {
+		os.Setenv("LOGIN_LOCKOUT_DURATION", "15m")
+	}
+
+	// Argon2id cost for new password hashes; older hashes are upgraded on login
+	if os.Getenv("ARGON2_TIME") == "" {
+		os.Setenv("ARGON2_TIME", "1")
+	}
+
+	if os.Getenv("ARGON2_MEMORY") == "" {
+		os.Setenv("ARGON2_MEMORY", "65536")
+	}
+
+	if os.Getenv("ARGON2_THREADS") == "" {
+		os.Setenv("ARGON2_THREADS", "4")
+	}
+
+	if os.Getenv("PASSWORD_MIN_LENGTH") == "" {
+		os.Setenv("PASSWORD_MIN_LENGTH", "8")
+	}
+
+	if os.Getenv("API_KEY_RATE_LIMIT") == "" {
+		os.Setenv("API_KEY_RATE_LIMIT", "60")
 	}
 }
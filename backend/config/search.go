@@ -0,0 +1,38 @@
+package config
+
+import "fmt"
+
+// searchMigrations set up full-text and fuzzy product search. The search
+// vector is maintained by a trigger so every write path keeps it current,
+// weighting matches in the name above the SKU above the description.
+var searchMigrations = []string{
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+	`ALTER TABLE products ADD COLUMN IF NOT EXISTS search_vector tsvector`,
+	`CREATE OR REPLACE FUNCTION products_search_vector_update() RETURNS trigger AS $$
+	BEGIN
+		NEW.search_vector :=
+			setweight(to_tsvector('english', coalesce(NEW.name, '')), 'A') ||
+			setweight(to_tsvector('english', coalesce(NEW.sku, '')), 'B') ||
+			setweight(to_tsvector('english', coalesce(NEW.description, '')), 'C');
+		RETURN NEW;
+	END
+	$$ LANGUAGE plpgsql`,
+	`DROP TRIGGER IF EXISTS products_search_vector_trigger ON products`,
+	`CREATE TRIGGER products_search_vector_trigger
+		BEFORE INSERT OR UPDATE OF name, sku, description ON products
+		FOR EACH ROW EXECUTE FUNCTION products_search_vector_update()`,
+	// Touching name fires the trigger for rows created before it existed
+	`UPDATE products SET name = name WHERE search_vector IS NULL`,
+	`CREATE INDEX IF NOT EXISTS idx_products_search_vector ON products USING GIN (search_vector)`,
+	`CREATE INDEX IF NOT EXISTS idx_products_name_trgm ON products USING GIN (name gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_products_sku_trgm ON products USING GIN (sku gin_trgm_ops)`,
+}
+
+func migrateSearch() error {
+	for _, statement := range searchMigrations {
+		if err := DB.Exec(statement).Error; err != nil {
+			return fmt.Errorf("failed to set up product search: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,23 @@
+package config
+
+import (
+	"fmt"
+
+	"backend/models"
+)
+
+// needsEmailVerificationBackfill reports whether the users table predates
+// email verification, checked before migrating adds the column
+func needsEmailVerificationBackfill() bool {
+	return DB.Migrator().HasTable(&models.User{}) && !DB.Migrator().HasColumn(&models.User{}, "email_verified_at")
+}
+
+// backfillEmailVerification treats accounts created before email
+// verification existed as verified, so requiring verification does not lock
+// existing customers out of checkout. It runs once, when the column is added.
+func backfillEmailVerification() error {
+	if err := DB.Exec(`UPDATE users SET email_verified_at = created_at WHERE email_verified_at IS NULL`).Error; err != nil {
+		return fmt.Errorf("failed to backfill email verification: %w", err)
+	}
+	return nil
+}
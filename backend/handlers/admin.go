@@ -3,21 +3,54 @@ package handlers
 import (
 	"backend/config"
 	"backend/models"
+	"backend/services"
+	"fmt"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
 )
 
 // AdminGetProducts returns all products for admin management
 func AdminGetProducts(c *fiber.Ctx) error {
 	var products []models.Product
 	
-	query := config.DB.Preload("Category")
+	query := adminProductsQuery(c)
+	
+	// Pagination
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 20)
+	offset := (page - 1) * limit
+	
+	if err := query.Offset(offset).Limit(limit).Find(&products).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch products",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"products": products,
+		"page":     page,
+		"limit":    limit,
+	})
+}
+
+// adminProductsQuery applies the admin product list filters and sort order
+func adminProductsQuery(c *fiber.Ctx) *gorm.DB {
+	query := config.DB.Preload("Category").Preload("Brand")
 	
 	// Filter by category
 	if categorySlug := c.Query("category"); categorySlug != "" {
 		query = query.Joins("JOIN categories ON categories.id = products.category_id").
 			Where("categories.slug = ?", categorySlug)
 	}
+
+	// Filter by brand
+	if brandSlug := c.Query("brand"); brandSlug != "" {
+		query = query.Where("products.brand_id IN (?)",
+			config.DB.Model(&models.Brand{}).Select("id").Where("slug = ?", brandSlug))
+	}
 	
 	// Search by name/description
 	if searchTerm := c.Query("q"); searchTerm != "" {
@@ -37,23 +70,8 @@ func AdminGetProducts(c *fiber.Ctx) error {
 		order = "desc"
 	}
 	query = query.Order("products." + sortBy + " " + order)
-	
-	// Pagination
-	page := c.QueryInt("page", 1)
-	limit := c.QueryInt("limit", 20)
-	offset := (page - 1) * limit
-	
-	if err := query.Offset(offset).Limit(limit).Find(&products).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to fetch products",
-		})
-	}
 
-	return c.JSON(fiber.Map{
-		"products": products,
-		"page":     page,
-		"limit":    limit,
-	})
+	return query
 }
 
 // AdminCreateProduct creates a new product
@@ -63,11 +81,13 @@ func AdminCreateProduct(c *fiber.Ctx) error {
 		Name          string      `json:"name"`
 		Slug          string      `json:"slug"`
 		CategoryID    string      `json:"category_id"`
+		BrandID       string      `json:"brand_id"`
 		Description   string      `json:"description"`
 		Price         float64     `json:"price"`
-		StockQuantity int         `json:"stock_quantity"`
+		StockQuantity float64     `json:"stock_quantity"`
 		ImagesJSON    []string    `json:"images_json"`
 		IsActive      bool        `json:"is_active"`
+		MeasureRequest
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -95,9 +115,16 @@ func AdminCreateProduct(c *fiber.Ctx) error {
 		})
 	}
 
+	measure := req.MeasureRequest.apply(defaultMeasure())
+	if err := measure.Validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
 	// Check if SKU already exists
 	var existingProduct models.Product
-	if err := config.DB.Where("sku = ?", req.SKU).First(&existingProduct).Error; err == nil {
+	if skuTaken(req.SKU, "") {
 		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
 			"error": "Product with this SKU already exists",
 		})
@@ -118,17 +145,31 @@ func AdminCreateProduct(c *fiber.Ctx) error {
 		})
 	}
 
+	// Verify brand exists; products need not have one
+	var brandID *uuid.UUID
+	if req.BrandID != "" {
+		var brand models.Brand
+		if err := config.DB.First(&brand, "id = ?", req.BrandID).Error; err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Brand not found",
+			})
+		}
+		brandID = &brand.ID
+	}
+
 	// Create product
 	product := models.Product{
 		SKU:           req.SKU,
 		Name:          req.Name,
 		Slug:          req.Slug,
 		CategoryID:    uuid.FromStringOrNil(req.CategoryID),
+		BrandID:       brandID,
 		Description:   req.Description,
 		Price:         req.Price,
-		StockQuantity: req.StockQuantity,
+		StockQuantity: models.RoundQuantity(req.StockQuantity),
 		ImagesJSON:    req.ImagesJSON,
 		IsActive:      req.IsActive,
+		Measure:       measure,
 	}
 
 	if err := config.DB.Create(&product).Error; err != nil {
@@ -137,6 +178,8 @@ func AdminCreateProduct(c *fiber.Ctx) error {
 		})
 	}
 
+	recordPriceChange(c, models.PriceChange{ProductID: product.ID, Price: product.Price})
+
 	return c.Status(fiber.StatusCreated).JSON(product)
 }
 
@@ -154,11 +197,13 @@ func AdminUpdateProduct(c *fiber.Ctx) error {
 		Name          *string   `json:"name"`
 		Slug          *string   `json:"slug"`
 		CategoryID    *string   `json:"category_id"`
+		BrandID       *string   `json:"brand_id"` // "" removes the brand
 		Description   *string   `json:"description"`
 		Price         *float64  `json:"price"`
-		StockQuantity *int      `json:"stock_quantity"`
+		StockQuantity *float64  `json:"stock_quantity"`
 		ImagesJSON    []string  `json:"images_json"`
 		IsActive      *bool     `json:"is_active"`
+		MeasureRequest
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -217,6 +262,20 @@ func AdminUpdateProduct(c *fiber.Ctx) error {
 		}
 		updates["category_id"] = *req.CategoryID
 	}
+
+	if req.BrandID != nil {
+		if *req.BrandID == "" {
+			updates["brand_id"] = nil
+		} else {
+			var brand models.Brand
+			if err := config.DB.First(&brand, "id = ?", *req.BrandID).Error; err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Brand not found",
+				})
+			}
+			updates["brand_id"] = brand.ID
+		}
+	}
 	
 	if req.Description != nil {
 		updates["description"] = *req.Description
@@ -232,12 +291,36 @@ func AdminUpdateProduct(c *fiber.Ctx) error {
 	}
 	
 	if req.StockQuantity != nil {
+		if product.IsBundle {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": services.ErrBundleStock.Error(),
+			})
+		}
 		if *req.StockQuantity < 0 {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 				"error": "Stock quantity must be non-negative",
 			})
 		}
-		updates["stock_quantity"] = *req.StockQuantity
+		updates["stock_quantity"] = models.RoundQuantity(*req.StockQuantity)
+	}
+
+	if req.MeasureRequest.changed() {
+		measure := req.MeasureRequest.apply(product.Measure)
+		if err := measure.Validate(); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if product.IsBundle && !measure.IsCountable() {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Bundles must be sold by the piece or box",
+			})
+		}
+		updates["unit"] = measure.Unit
+		updates["unit_size"] = measure.UnitSize
+		updates["quantity_step"] = measure.QuantityStep
+		updates["purchase_unit"] = measure.PurchaseUnit
+		updates["purchase_unit_size"] = measure.PurchaseUnitSize
 	}
 	
 	if req.ImagesJSON != nil {
@@ -248,12 +331,18 @@ func AdminUpdateProduct(c *fiber.Ctx) error {
 		updates["is_active"] = *req.IsActive
 	}
 
+	priceChanged := req.Price != nil && *req.Price != product.Price
+
 	if err := config.DB.Model(&product).Updates(updates).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update product",
 		})
 	}
 
+	if priceChanged {
+		recordPriceChange(c, models.PriceChange{ProductID: product.ID, Price: *req.Price, SalePricing: product.SalePricing})
+	}
+
 	return c.JSON(product)
 }
 
@@ -280,6 +369,44 @@ func AdminDeleteProduct(c *fiber.Ctx) error {
 		})
 	}
 
+	inBundle, err := services.NewBundleService().IsComponent(id, nil)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check product usage",
+		})
+	}
+
+	if inBundle {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Cannot delete a product that is part of a bundle",
+		})
+	}
+
+	if err := config.DB.Where("bundle_id = ?", id).Delete(&models.BundleComponent{}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete bundle components",
+		})
+	}
+
+	// Remove the product's price tiers and variants along with it
+	if err := config.DB.Where("product_id = ?", id).Delete(&models.PriceTier{}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete product price tiers",
+		})
+	}
+
+	if err := config.DB.Where("product_id = ?", id).Delete(&models.ProductVariant{}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete product variants",
+		})
+	}
+
+	if err := services.NewRecommendationService().DeleteForProduct(config.DB, id); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete related products",
+		})
+	}
+
 	// Soft delete the product
 	if err := config.DB.Delete(&models.Product{}, "id = ?", id).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -328,16 +455,17 @@ func AdminGetSalesReport(c *fiber.Ctx) error {
 	// Get top selling products
 	var topProducts []struct {
 		ProductName string  `json:"product_name"`
-		TotalSold  int     `json:"total_sold"`
+		Unit       string  `json:"unit"`
+		TotalSold  float64 `json:"total_sold"`
 		Revenue    float64 `json:"revenue"`
 	}
 
 	if err := config.DB.Table("order_items").
-		Select("products.name as product_name, SUM(order_items.quantity) as total_sold, SUM(order_items.quantity * order_items.unit_price) as revenue").
+		Select("products.name as product_name, products.unit as unit, SUM(order_items.quantity) as total_sold, SUM(order_items.quantity * order_items.unit_price) as revenue").
 		Joins("JOIN products ON products.id = order_items.product_id").
 		Joins("JOIN orders ON orders.id = order_items.order_id").
 		Where("orders.placed_at BETWEEN ? AND ?", startDate, endDate).
-		Group("products.id, products.name").
+		Group("products.id, products.name, products.unit").
 		Order("total_sold DESC").
 		Limit(10).
 		Scan(&topProducts).Error; err != nil {
@@ -359,7 +487,7 @@ func AdminGetInventoryReport(c *fiber.Ctx) error {
 	// Get low stock products (less than 10 items)
 	var lowStockProducts []models.Product
 	if err := config.DB.Preload("Category").
-		Where("stock_quantity < 10 AND is_active = ?", true).
+		Where("stock_quantity < 10 AND is_active = ? AND is_bundle = ?", true, false).
 		Order("stock_quantity ASC").
 		Find(&lowStockProducts).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -369,8 +497,9 @@ func AdminGetInventoryReport(c *fiber.Ctx) error {
 
 	// Get total inventory value
 	var totalValue float64
+	// Bundle stock is their components' stock, which is already counted
 	if err := config.DB.Model(&models.Product{}).
-		Where("is_active = ?", true).
+		Where("is_active = ? AND is_bundle = ?", true, false).
 		Select("COALESCE(SUM(stock_quantity * price), 0)").
 		Scan(&totalValue).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -400,8 +529,10 @@ func AdminGetInventoryReport(c *fiber.Ctx) error {
 func AdminUpdateStock(c *fiber.Ctx) error {
 	var req struct {
 		ProductID string `json:"product_id"`
-		Quantity  int    `json:"quantity"`
-		Operation string `json:"operation"` // "add", "subtract", "set"
+		VariantID string `json:"variant_id"`
+		Quantity  float64 `json:"quantity"`
+		Operation string  `json:"operation"` // "add", "subtract", "set"
+		Unit      string  `json:"unit"`      // "purchase" to count in purchase units, e.g. drums received
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -423,17 +554,43 @@ func AdminUpdateStock(c *fiber.Ctx) error {
 		})
 	}
 
-	var newQuantity int
+	if product.IsBundle {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": services.ErrBundleStock.Error() + "; adjust its components instead",
+		})
+	}
+
+	// Stock is held on the variant when one is given
+	var variant models.ProductVariant
+	stockRecord := config.DB.Model(&product)
+	oldQuantity := product.StockQuantity
+	if req.VariantID != "" {
+		if err := config.DB.First(&variant, "id = ? AND product_id = ?", req.VariantID, product.ID).Error; err != nil {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "Variant not found",
+			})
+		}
+		stockRecord = config.DB.Model(&variant)
+		oldQuantity = variant.StockQuantity
+	}
+
+	// Stock is kept in sales units
+	quantity := models.RoundQuantity(req.Quantity)
+	if req.Unit == "purchase" {
+		quantity = product.FromPurchaseUnits(req.Quantity)
+	}
+
+	var newQuantity float64
 	switch req.Operation {
 	case "add":
-		newQuantity = product.StockQuantity + req.Quantity
+		newQuantity = models.RoundQuantity(oldQuantity + quantity)
 	case "subtract":
-		newQuantity = product.StockQuantity - req.Quantity
+		newQuantity = models.RoundQuantity(oldQuantity - quantity)
 		if newQuantity < 0 {
 			newQuantity = 0
 		}
 	case "set":
-		newQuantity = req.Quantity
+		newQuantity = quantity
 		if newQuantity < 0 {
 			newQuantity = 0
 		}
@@ -443,27 +600,34 @@ func AdminUpdateStock(c *fiber.Ctx) error {
 		})
 	}
 
-	if err := config.DB.Model(&product).Update("stock_quantity", newQuantity).Error; err != nil {
+	if err := stockRecord.Update("stock_quantity", newQuantity).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update stock",
 		})
 	}
 
-	return c.JSON(fiber.Map{
+	response := fiber.Map{
 		"message": "Stock updated successfully",
 		"product_id": product.ID,
-		"old_quantity": product.StockQuantity,
+		"old_quantity": oldQuantity,
 		"new_quantity": newQuantity,
-	})
+		"unit": product.Unit,
+	}
+	if req.VariantID != "" {
+		response["variant_id"] = variant.ID
+	}
+
+	return c.JSON(response)
 }
 
 // AdminGetLowStockItems returns products with low stock
 func AdminGetLowStockItems(c *fiber.Ctx) error {
 	threshold := c.QueryInt("threshold", 10)
 
+	// Bundles are left out since they are restocked through their components
 	var products []models.Product
 	if err := config.DB.Preload("Category").
-		Where("stock_quantity <= ?", threshold).
+		Where("stock_quantity <= ? AND is_bundle = ?", threshold, false).
 		Order("stock_quantity ASC").
 		Find(&products).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -471,10 +635,21 @@ func AdminGetLowStockItems(c *fiber.Ctx) error {
 		})
 	}
 
+	var variants []models.ProductVariant
+	if err := config.DB.Preload("Product").
+		Where("stock_quantity <= ? AND is_active = ?", threshold, true).
+		Order("stock_quantity ASC").
+		Find(&variants).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch low stock items",
+		})
+	}
+
 	return c.JSON(fiber.Map{
 		"threshold": threshold,
 		"products": products,
-		"count": len(products),
+		"variants": variants,
+		"count": len(products) + len(variants),
 	})
 }
 
@@ -488,7 +663,7 @@ func AdminGetOrderDetails(c *fiber.Ctx) error {
 	}
 
 	var order models.Order
-	if err := config.DB.Preload("OrderItems.Product.Category").
+	if err := config.DB.Preload("OrderItems.Product.Category").Preload("OrderItems.Variant").
 		Preload("User").
 		Preload("Payments").
 		Where("id = ?", orderID).
@@ -671,6 +846,11 @@ func AdminGetUsers(c *fiber.Ctx) error {
 			"%"+search+"%", "%"+search+"%")
 	}
 
+	// Filter by lockout status
+	if locked := c.Query("locked"); locked == "true" {
+		query = query.Where("locked_until > ?", time.Now())
+	}
+
 	// Pagination
 	page := c.QueryInt("page", 1)
 	limit := c.QueryInt("limit", 20)
@@ -714,9 +894,16 @@ func AdminUpdateUserRole(c *fiber.Ctx) error {
 	}
 
 	// Validate role
-	if req.Role != "customer" && req.Role != "admin" {
+	if _, err := services.NewRoleService().GetRole(req.Role); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid role. Use 'customer' or 'admin'",
+			"error": "Invalid role",
+		})
+	}
+
+	// Prevent admins from locking themselves out
+	if userID == c.Locals("user_id") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "You cannot change your own role",
 		})
 	}
 
@@ -734,6 +921,11 @@ func AdminUpdateUserRole(c *fiber.Ctx) error {
 		})
 	}
 
+	// The role is embedded in access tokens, so force the user to sign in again
+	if err := services.NewSessionService().RevokeUserSessions(user.ID.String(), models.SessionRevokedAdmin); err != nil {
+		fmt.Printf("Failed to revoke sessions after role change: %v\n", err)
+	}
+
 	return c.JSON(fiber.Map{
 		"message": "User role updated successfully",
 		"role":    req.Role,
@@ -770,6 +962,13 @@ func AdminDeleteUser(c *fiber.Ctx) error {
 		})
 	}
 
+	// Revoke sessions first so outstanding tokens die with the account
+	if err := services.NewSessionService().RevokeUserSessions(user.ID.String(), models.SessionRevokedAdmin); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke user sessions",
+		})
+	}
+
 	// Delete user (this will cascade to related records)
 	if err := config.DB.Delete(&user).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -782,6 +981,60 @@ func AdminDeleteUser(c *fiber.Ctx) error {
 	})
 }
 
+// AdminUnlockUser lifts a failed-login lockout on a user account
+func AdminUnlockUser(c *fiber.Ctx) error {
+	userID := c.Params("id")
+	if userID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "User ID is required",
+		})
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, "id = ?", userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	if err := services.NewLoginProtectionService().Unlock(&user); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to unlock user",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "User unlocked successfully",
+	})
+}
+
+// AdminRevokeUserSessions signs a user out of every device
+func AdminRevokeUserSessions(c *fiber.Ctx) error {
+	userID := c.Params("id")
+	if userID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "User ID is required",
+		})
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, "id = ?", userID).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	if err := services.NewSessionService().RevokeUserSessions(user.ID.String(), models.SessionRevokedAdmin); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke user sessions",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "User sessions revoked successfully",
+	})
+}
+
 // AdminGetUsersReport returns a report of user statistics
 func AdminGetUsersReport(c *fiber.Ctx) error {
 	var totalUsers int64
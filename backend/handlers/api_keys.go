@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"errors"
+	"time"
+
+	"backend/models"
+	"backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type CreateAPIKeyRequest struct {
+	Name      string              `json:"name"`
+	Scopes    []models.Permission `json:"scopes"`
+	RateLimit int                 `json:"rate_limit"`
+	ExpiresAt *time.Time          `json:"expires_at"`
+}
+
+// AdminGetAPIKeys lists issued API keys without their secrets
+func AdminGetAPIKeys(c *fiber.Ctx) error {
+	keys, err := services.NewAPIKeyService().ListKeys()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch API keys",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"api_keys": keys,
+	})
+}
+
+// AdminCreateAPIKey issues a scoped API key. The key is only shown in this response.
+func AdminCreateAPIKey(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if user == nil {
+		return err
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Name is required",
+		})
+	}
+
+	if req.ExpiresAt != nil && req.ExpiresAt.Before(time.Now()) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Expiry must be in the future",
+		})
+	}
+
+	key, rawKey, err := services.NewAPIKeyService().CreateKey(user, req.Name, req.Scopes, req.RateLimit, req.ExpiresAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrAPIKeyScopeRequired),
+			errors.Is(err, services.ErrAPIKeyScopeDenied),
+			errors.Is(err, services.ErrUnknownPermission):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to create API key",
+			})
+		}
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "API key created. Store it now, it will not be shown again",
+		"api_key": key,
+		"key":     rawKey,
+	})
+}
+
+// AdminRevokeAPIKey permanently disables an API key
+func AdminRevokeAPIKey(c *fiber.Ctx) error {
+	keyID := c.Params("id")
+	if keyID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "API key ID is required",
+		})
+	}
+
+	if err := services.NewAPIKeyService().RevokeKey(keyID); err != nil {
+		if errors.Is(err, services.ErrAPIKeyNotFound) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": "API key not found or already revoked",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke API key",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "API key revoked successfully",
+	})
+}
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"backend/config"
+	"backend/models"
+	"backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type AttributeRequest struct {
+	Name         *string                 `json:"name"`
+	Slug         *string                 `json:"slug"`
+	Type         *models.AttributeType   `json:"type"`
+	Unit         *string                 `json:"unit"`
+	Options      models.AttributeOptions `json:"options"`
+	IsFilterable *bool                   `json:"is_filterable"`
+	Position     *int                    `json:"position"`
+}
+
+// AdminGetCategoryAttributes lists the attributes that apply to a category,
+// including those inherited from its parents
+func AdminGetCategoryAttributes(c *fiber.Ctx) error {
+	var category models.Category
+	if err := config.DB.First(&category, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Category not found",
+		})
+	}
+
+	definitions, err := services.NewAttributeService().DefinitionsForCategory(category.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch attributes",
+		})
+	}
+
+	return c.JSON(definitions)
+}
+
+// AdminCreateCategoryAttribute defines a new attribute on a category
+func AdminCreateCategoryAttribute(c *fiber.Ctx) error {
+	var category models.Category
+	if err := config.DB.First(&category, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Category not found",
+		})
+	}
+
+	var req AttributeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if req.Name == nil || req.Slug == nil || req.Type == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Name, slug and type are required",
+		})
+	}
+
+	definition := models.AttributeDefinition{
+		CategoryID:   category.ID,
+		Name:         *req.Name,
+		Slug:         *req.Slug,
+		Type:         *req.Type,
+		Options:      req.Options,
+		IsFilterable: true,
+	}
+	if req.Unit != nil {
+		definition.Unit = *req.Unit
+	}
+	if req.IsFilterable != nil {
+		definition.IsFilterable = *req.IsFilterable
+	}
+	if req.Position != nil {
+		definition.Position = *req.Position
+	}
+
+	attributeService := services.NewAttributeService()
+	if err := attributeService.ValidateDefinition(&definition); err != nil {
+		return attributeError(c, err)
+	}
+
+	if err := config.DB.Create(&definition).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create attribute",
+		})
+	}
+
+	// GORM skips false for fields with a default, so store it explicitly
+	if !definition.IsFilterable {
+		config.DB.Model(&definition).Update("is_filterable", false)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(definition)
+}
+
+// AdminUpdateAttribute updates an attribute definition. The type cannot be
+// changed once defined, since existing values are stored by type.
+func AdminUpdateAttribute(c *fiber.Ctx) error {
+	var definition models.AttributeDefinition
+	if err := config.DB.First(&definition, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Attribute not found",
+		})
+	}
+
+	var req AttributeRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if req.Type != nil && *req.Type != definition.Type {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Attribute type cannot be changed; create a new attribute instead",
+		})
+	}
+
+	updates := map[string]interface{}{}
+
+	if req.Name != nil {
+		definition.Name = *req.Name
+		updates["name"] = *req.Name
+	}
+	if req.Slug != nil {
+		definition.Slug = *req.Slug
+		updates["slug"] = *req.Slug
+	}
+	if req.Unit != nil {
+		updates["unit"] = *req.Unit
+	}
+	if req.Options != nil {
+		definition.Options = req.Options
+		updates["options"] = req.Options
+	}
+	if req.IsFilterable != nil {
+		updates["is_filterable"] = *req.IsFilterable
+	}
+	if req.Position != nil {
+		updates["position"] = *req.Position
+	}
+
+	if err := services.NewAttributeService().ValidateDefinition(&definition); err != nil {
+		return attributeError(c, err)
+	}
+
+	if err := config.DB.Model(&definition).Updates(updates).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update attribute",
+		})
+	}
+
+	config.DB.First(&definition, "id = ?", definition.ID)
+
+	return c.JSON(definition)
+}
+
+// AdminDeleteAttribute deletes an attribute definition and its product values
+func AdminDeleteAttribute(c *fiber.Ctx) error {
+	if err := services.NewAttributeService().DeleteDefinition(c.Params("id")); err != nil {
+		return attributeError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Attribute deleted successfully",
+	})
+}
+
+// AdminSetProductAttributes sets a product's attribute values, keyed by
+// attribute slug. A null value removes the attribute from the product.
+func AdminSetProductAttributes(c *fiber.Ctx) error {
+	var product models.Product
+	if err := config.DB.First(&product, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Product not found",
+		})
+	}
+
+	var values map[string]interface{}
+	if err := c.BodyParser(&values); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	attributeService := services.NewAttributeService()
+	if err := attributeService.SetProductValues(&product, values); err != nil {
+		return attributeError(c, err)
+	}
+
+	attributes, err := attributeService.GetProductAttributes(product.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch product attributes",
+		})
+	}
+
+	return c.JSON(attributes)
+}
+
+// parseAttributeFilters reads attr.<slug>=a,b and attr.<slug>.min/.max query parameters
+func parseAttributeFilters(c *fiber.Ctx) ([]services.AttributeFilter, error) {
+	filters := map[string]*services.AttributeFilter{}
+	filter := func(slug string) *services.AttributeFilter {
+		if filters[slug] == nil {
+			filters[slug] = &services.AttributeFilter{Slug: slug}
+		}
+		return filters[slug]
+	}
+
+	for key, value := range c.Queries() {
+		name, ok := strings.CutPrefix(key, "attr.")
+		if !ok || name == "" || value == "" {
+			continue
+		}
+
+		if slug, ok := strings.CutSuffix(name, ".min"); ok {
+			lower, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be a number", key)
+			}
+			filter(slug).Min = &lower
+			continue
+		}
+
+		if slug, ok := strings.CutSuffix(name, ".max"); ok {
+			upper, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s must be a number", key)
+			}
+			filter(slug).Max = &upper
+			continue
+		}
+
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); v != "" {
+				filter(name).Values = append(filter(name).Values, v)
+			}
+		}
+	}
+
+	result := make([]services.AttributeFilter, 0, len(filters))
+	for _, f := range filters {
+		result = append(result, *f)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Slug < result[j].Slug })
+
+	return result, nil
+}
+
+func attributeError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrAttributeNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Attribute not found"})
+	case errors.Is(err, services.ErrAttributeExists):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidAttribute), errors.Is(err, services.ErrUnknownAttribute),
+		errors.Is(err, services.ErrInvalidAttributeValue):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save attribute"})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"backend/models"
 	"backend/services"
 	"backend/utils"
+	"errors"
 	"fmt"
 	"time"
 
@@ -24,9 +25,17 @@ type LoginRequest struct {
 	Password string `json:"password"`
 }
 
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool   `json:"two_factor_required"`
+	ChallengeToken    string `json:"challenge_token"`
+	ExpiresIn         int64  `json:"expires_in"`
+}
+
 type AuthResponse struct {
-	Token string      `json:"token"`
-	User  models.User `json:"user"`
+	Token        string      `json:"token"`
+	RefreshToken string      `json:"refresh_token"`
+	ExpiresIn    int64       `json:"expires_in"`
+	User         models.User `json:"user"`
 }
 
 // Register handles user registration
@@ -45,9 +54,9 @@ func Register(c *fiber.Ctx) error {
 		})
 	}
 
-	if len(req.Password) < 6 {
+	if err := utils.ValidatePassword(req.Password, req.Email); err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Password must be at least 6 characters",
+			"error": err.Error(),
 		})
 	}
 
@@ -82,8 +91,22 @@ func Register(c *fiber.Ctx) error {
 		})
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateJWT(user.ID.String(), user.Email, string(user.Role))
+	// Send verification email and SMS asynchronously (don't block the response)
+	verificationUser := user
+	go func() {
+		verificationService := services.NewVerificationService()
+		if err := verificationService.SendEmailVerification(&verificationUser); err != nil {
+			fmt.Printf("Failed to send verification email: %v\n", err)
+		}
+		if verificationUser.Phone != nil && *verificationUser.Phone != "" {
+			if err := verificationService.SendPhoneVerification(&verificationUser); err != nil {
+				fmt.Printf("Failed to send verification SMS: %v\n", err)
+			}
+		}
+	}()
+
+	// Start a session and issue tokens
+	tokens, err := services.NewSessionService().CreateSession(&user, c.Get("User-Agent"), c.IP(), false)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to generate token",
@@ -94,8 +117,10 @@ func Register(c *fiber.Ctx) error {
 	user.PasswordHash = ""
 
 	return c.Status(fiber.StatusCreated).JSON(AuthResponse{
-		Token: token,
-		User:  user,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         user,
 	})
 }
 
@@ -115,21 +140,55 @@ func Login(c *fiber.Ctx) error {
 		})
 	}
 
+	// Enforce the per-account delay between failed attempts
+	loginProtection := services.NewLoginProtectionService()
+	if retryAfter := loginProtection.RetryAfter(req.Email); retryAfter > 0 {
+		return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+			"error":       "Too many failed attempts. Please wait before trying again",
+			"retry_after": int(retryAfter.Seconds()) + 1,
+		})
+	}
+
 	// Find user by email
 	var user models.User
 	if err := config.DB.Where("email = ?", req.Email).First(&user).Error; err != nil {
+		loginProtection.RecordFailure(req.Email, nil)
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Invalid credentials",
 		})
 	}
 
-	// Verify password
-	if !utils.VerifyPassword(req.Password, user.PasswordHash) {
+	// Verify password. Every attempt on a locked account gets the same
+	// answer as a wrong password, whatever password is sent, and pushes the
+	// lock back, so the lock can be neither detected nor used to test
+	// guesses; the owner has the emailed unlock link. The password is hashed
+	// either way so timing gives nothing away either.
+	passwordValid := utils.VerifyPassword(req.Password, user.PasswordHash)
+	if user.IsLocked() || !passwordValid {
+		loginProtection.RecordFailure(req.Email, &user)
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 			"error": "Invalid credentials",
 		})
 	}
 
+	loginProtection.RecordSuccess(req.Email)
+
+	// Upgrade hashes made with older parameters while the password is at hand
+	if utils.PasswordNeedsRehash(user.PasswordHash) {
+		if hashedPassword, err := utils.HashPassword(req.Password); err == nil {
+			if err := config.DB.Model(&user).Update("password_hash", hashedPassword).Error; err != nil {
+				fmt.Printf("Failed to upgrade password hash: %v\n", err)
+			}
+		}
+	}
+
+	return completeLogin(c, &user)
+}
+
+// completeLogin finishes signing in a user whose first factor has been
+// checked: it rejects deactivated accounts, hands out a two-factor challenge
+// when enrolled, and otherwise starts a session and issues tokens
+func completeLogin(c *fiber.Ctx, user *models.User) error {
 	// Check if user is active
 	if !user.IsActive {
 		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
@@ -137,8 +196,24 @@ func Login(c *fiber.Ctx) error {
 		})
 	}
 
-	// Generate JWT token
-	token, err := utils.GenerateJWT(user.ID.String(), user.Email, string(user.Role))
+	// Users with two-factor authentication finish signing in via /auth/2fa/verify
+	if user.TwoFactorEnabled {
+		challengeToken, err := utils.GenerateChallengeToken(user.ID.String(), utils.ChallengePurposeTwoFactor, services.TwoFactorChallengeExpiry)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to generate token",
+			})
+		}
+
+		return c.JSON(TwoFactorChallengeResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challengeToken,
+			ExpiresIn:         int64(services.TwoFactorChallengeExpiry.Seconds()),
+		})
+	}
+
+	// Start a session and issue tokens
+	tokens, err := services.NewSessionService().CreateSession(user, c.Get("User-Agent"), c.IP(), false)
 	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to generate token",
@@ -149,8 +224,56 @@ func Login(c *fiber.Ctx) error {
 	user.PasswordHash = ""
 
 	return c.JSON(AuthResponse{
-		Token: token,
-		User:  user,
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         *user,
+	})
+}
+
+// RefreshToken exchanges a refresh token for a new access and refresh token pair
+func RefreshToken(c *fiber.Ctx) error {
+	var req struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if req.RefreshToken == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Refresh token is required",
+		})
+	}
+
+	tokens, user, err := services.NewSessionService().Refresh(req.RefreshToken, c.Get("User-Agent"), c.IP())
+	if err != nil {
+		if errors.Is(err, services.ErrRefreshTokenReused) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Refresh token has already been used; session revoked",
+			})
+		}
+		if errors.Is(err, services.ErrInvalidRefreshToken) {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid or expired refresh token",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to refresh token",
+		})
+	}
+
+	// Clear password hash from response
+	user.PasswordHash = ""
+
+	return c.JSON(AuthResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         *user,
 	})
 }
 
@@ -196,12 +319,24 @@ func UpdateProfile(c *fiber.Ctx) error {
 		})
 	}
 
+	var user models.User
+	if err := config.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
 	// Update user
 	updates := map[string]interface{}{
 		"full_name": req.FullName,
 		"phone":     req.Phone,
 	}
 
+	// A new phone number has to be verified again
+	if req.Phone == nil || user.Phone == nil || *req.Phone != *user.Phone {
+		updates["phone_verified_at"] = nil
+	}
+
 	if err := config.DB.Model(&models.User{}).Where("id = ?", userID).Updates(updates).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update profile",
@@ -213,16 +348,55 @@ func UpdateProfile(c *fiber.Ctx) error {
 	})
 }
 
-// Logout handles user logout
+// Logout revokes the current session so its access and refresh tokens stop working
 func Logout(c *fiber.Ctx) error {
-	// In a JWT-based system, logout is typically handled client-side by removing the token
-	// However, we can implement token blacklisting if needed
-	// For now, we'll just return a success message
+	sessionID := c.Locals("session_id")
+	if sessionID == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User not authenticated",
+		})
+	}
+
+	if err := services.NewSessionService().RevokeSession(sessionID.(string), models.SessionRevokedLogout); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to log out",
+		})
+	}
+
 	return c.JSON(fiber.Map{
 		"message": "Logged out successfully",
 	})
 }
 
+// UnlockAccount lifts a lockout using the token from the account locked email
+func UnlockAccount(c *fiber.Ctx) error {
+	var req struct {
+		Token string `json:"token"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if req.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Token is required",
+		})
+	}
+
+	if _, err := services.NewLoginProtectionService().UnlockWithToken(req.Token); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid or expired unlock token",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Account unlocked successfully",
+	})
+}
+
 // RequestPasswordReset handles password reset requests
 func RequestPasswordReset(c *fiber.Ctx) error {
 	var req struct {
@@ -295,12 +469,6 @@ func ConfirmPasswordReset(c *fiber.Ctx) error {
 		})
 	}
 
-	if len(req.Password) < 6 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Password must be at least 6 characters",
-		})
-	}
-
 	// Find user by reset token
 	var user models.User
 	if err := config.DB.Where("reset_token = ? AND reset_token_expiry > ?", req.Token, time.Now()).First(&user).Error; err != nil {
@@ -309,6 +477,12 @@ func ConfirmPasswordReset(c *fiber.Ctx) error {
 		})
 	}
 
+	if err := utils.ValidatePassword(req.Password, user.Email); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
 	// Hash new password
 	hashedPassword, err := utils.HashPassword(req.Password)
 	if err != nil {
@@ -317,18 +491,45 @@ func ConfirmPasswordReset(c *fiber.Ctx) error {
 		})
 	}
 
-	// Update password and clear reset token
+	// Update password, clear reset token and lift any lockout
 	if err := config.DB.Model(&user).Updates(map[string]interface{}{
 		"password_hash":      hashedPassword,
 		"reset_token":        nil,
 		"reset_token_expiry": nil,
+		"locked_until":       nil,
 	}).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update password",
 		})
 	}
 
+	// Sign out every other device so a leaked token can't outlive the old
+	// password, then start a fresh session for the device that reset it
+	sessionService := services.NewSessionService()
+	if err := sessionService.RevokeUserSessions(user.ID.String(), models.SessionRevokedPasswordReset); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke existing sessions",
+		})
+	}
+
+	// A password reset must not bypass the second factor
+	if user.TwoFactorEnabled {
+		return c.JSON(fiber.Map{
+			"message": "Password reset successfully. Please sign in again",
+		})
+	}
+
+	tokens, err := sessionService.CreateSession(&user, c.Get("User-Agent"), c.IP(), false)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate token",
+		})
+	}
+
 	return c.JSON(fiber.Map{
-		"message": "Password reset successfully",
+		"message":       "Password reset successfully",
+		"token":         tokens.AccessToken,
+		"refresh_token": tokens.RefreshToken,
+		"expires_in":    tokens.ExpiresIn,
 	})
 }
@@ -0,0 +1,214 @@
+package handlers
+
+import (
+	"backend/config"
+	"backend/models"
+	"backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// GetBrands returns active brands with their product counts
+func GetBrands(c *fiber.Ctx) error {
+	brands, err := services.NewBrandService().List(false)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch brands",
+		})
+	}
+
+	return c.JSON(brands)
+}
+
+// GetBrandPage returns a brand with a page of its active products
+func GetBrandPage(c *fiber.Ctx) error {
+	brand, err := services.NewBrandService().GetBySlug(c.Params("slug"))
+	if err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Brand not found",
+		})
+	}
+
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 20)
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	query := config.DB.Model(&models.Product{}).Where("brand_id = ? AND is_active = ?", brand.ID, true)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch products",
+		})
+	}
+
+	var products []models.Product
+	if err := query.Preload("Category").
+		Order("name ASC").
+		Offset((page - 1) * limit).Limit(limit).
+		Find(&products).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch products",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"brand":    brand,
+		"products": products,
+		"total":    total,
+		"page":     page,
+		"limit":    limit,
+		"pages":    (total + int64(limit) - 1) / int64(limit),
+	})
+}
+
+// AdminGetBrands returns every brand, including inactive ones, with product counts
+func AdminGetBrands(c *fiber.Ctx) error {
+	brands, err := services.NewBrandService().List(true)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch brands",
+		})
+	}
+
+	return c.JSON(brands)
+}
+
+// AdminCreateBrand creates a new brand
+func AdminCreateBrand(c *fiber.Ctx) error {
+	var req struct {
+		Name        string `json:"name"`
+		Slug        string `json:"slug"`
+		Description string `json:"description"`
+		LogoURL     string `json:"logo_url"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if req.Name == "" || req.Slug == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Name and slug are required",
+		})
+	}
+
+	// Check if slug already exists
+	var existingBrand models.Brand
+	if err := config.DB.Where("slug = ?", req.Slug).First(&existingBrand).Error; err == nil {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Brand with this slug already exists",
+		})
+	}
+
+	brand := models.Brand{
+		Name:        req.Name,
+		Slug:        req.Slug,
+		Description: req.Description,
+		LogoURL:     req.LogoURL,
+		IsActive:    true,
+	}
+
+	if err := config.DB.Create(&brand).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create brand",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(brand)
+}
+
+// AdminUpdateBrand updates an existing brand
+func AdminUpdateBrand(c *fiber.Ctx) error {
+	var req struct {
+		Name        string  `json:"name"`
+		Slug        string  `json:"slug"`
+		Description *string `json:"description"`
+		LogoURL     *string `json:"logo_url"` // "" removes the logo
+		IsActive    *bool   `json:"is_active"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var brand models.Brand
+	if err := config.DB.First(&brand, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Brand not found",
+		})
+	}
+
+	updates := map[string]interface{}{}
+	if req.Name != "" {
+		updates["name"] = req.Name
+	}
+	if req.Slug != "" && req.Slug != brand.Slug {
+		var existingBrand models.Brand
+		if err := config.DB.Where("slug = ?", req.Slug).First(&existingBrand).Error; err == nil {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Brand with this slug already exists",
+			})
+		}
+		updates["slug"] = req.Slug
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+	if req.LogoURL != nil {
+		updates["logo_url"] = *req.LogoURL
+	}
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+
+	if err := config.DB.Model(&brand).Updates(updates).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update brand",
+		})
+	}
+
+	return c.JSON(brand)
+}
+
+// AdminDeleteBrand deletes a brand that no product uses
+func AdminDeleteBrand(c *fiber.Ctx) error {
+	var brand models.Brand
+	if err := config.DB.First(&brand, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Brand not found",
+		})
+	}
+
+	var productCount int64
+	if err := config.DB.Model(&models.Product{}).Where("brand_id = ?", brand.ID).Count(&productCount).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check brand usage",
+		})
+	}
+
+	if productCount > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Cannot delete brand with existing products; deactivate it instead",
+		})
+	}
+
+	if err := config.DB.Delete(&brand).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete brand",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Brand deleted successfully",
+	})
+}
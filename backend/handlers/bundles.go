@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"errors"
+
+	"backend/config"
+	"backend/models"
+	"backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// AdminGetBundleItems returns the components a bundle is made of
+func AdminGetBundleItems(c *fiber.Ctx) error {
+	var product models.Product
+	if err := config.DB.First(&product, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Product not found",
+		})
+	}
+
+	components, err := services.NewBundleService().GetComponents(product.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch bundle items",
+		})
+	}
+
+	return c.JSON(components)
+}
+
+// AdminSetBundleItems replaces a product's components, making it a bundle
+// whose stock is derived from theirs. An empty list unbundles it.
+func AdminSetBundleItems(c *fiber.Ctx) error {
+	var product models.Product
+	if err := config.DB.First(&product, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Product not found",
+		})
+	}
+
+	var req struct {
+		Items []services.BundleItemInput `json:"items"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	bundleService := services.NewBundleService()
+
+	if err := bundleService.SetComponents(&product, req.Items); err != nil {
+		if errors.Is(err, services.ErrInvalidBundle) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save bundle items",
+		})
+	}
+
+	components, err := bundleService.GetComponents(product.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch bundle items",
+		})
+	}
+	product.BundleItems = components
+
+	return c.JSON(product)
+}
@@ -1,16 +1,35 @@
 package handlers
 
 import (
+	"errors"
+	"strings"
+
 	"backend/config"
 	"backend/models"
+	"backend/services"
 
 	"github.com/gofiber/fiber/v2"
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
 )
 
-// GetCategories returns all categories
+type ProductDetailsResponse struct {
+	models.Product
+	Breadcrumbs []services.CategoryBreadcrumb `json:"breadcrumbs"`
+	Attributes  []services.ProductAttribute   `json:"attributes"`
+	PriceTiers  []models.PriceTier            `json:"price_tiers"`
+	Related     []models.Product              `json:"related_products"`
+}
+
+type ProductSearchResult struct {
+	models.Product
+	Highlight *services.SearchHighlight `json:"highlight,omitempty"`
+}
+
+// GetCategories returns the category tree with subcategories nested under children
 func GetCategories(c *fiber.Ctx) error {
-	var categories []models.Category
-	if err := config.DB.Find(&categories).Error; err != nil {
+	categories, err := services.NewCategoryService().GetTree()
+	if err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch categories",
 		})
@@ -19,16 +38,33 @@ func GetCategories(c *fiber.Ctx) error {
 	return c.JSON(categories)
 }
 
+// ProductListParams are the query parameters GetProducts reads
+var ProductListParams = []string{"category", "brand", "q", "min_price", "max_price", "sort", "order", "page", "limit"}
+
 // GetProducts returns products with optional filtering
 func GetProducts(c *fiber.Ctx) error {
 	var products []models.Product
 
-	query := config.DB.Preload("Category")
+	query := config.DB.Model(&models.Product{}).Preload("Category").Preload("Brand")
 
-	// Filter by category
+	// Filter by brand, e.g. brand=dangote,lafarge
+	brandService := services.NewBrandService()
+	brandIDs, err := brandService.ParseFilter(c.Query("brand"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch brands",
+		})
+	}
+
+	// Filter by category, including products in its subcategories
 	if categorySlug := c.Query("category"); categorySlug != "" {
-		query = query.Joins("JOIN categories ON categories.id = products.category_id").
-			Where("categories.slug = ?", categorySlug)
+		categoryIDs, err := services.NewCategoryService().GetDescendantIDsBySlug(categorySlug)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to fetch categories",
+			})
+		}
+		query = query.Where("products.category_id IN ?", categoryIDs)
 	}
 
 	// Search by name/description
@@ -45,6 +81,17 @@ func GetProducts(c *fiber.Ctx) error {
 		query = query.Where("products.price <= ?", maxPrice)
 	}
 
+	// Count brands over every filter except the brand filter itself, so
+	// shoppers can widen their brand choice
+	query = query.Session(&gorm.Session{})
+	brands, err := brandService.Facet(query.Select("products.id"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to count brands",
+		})
+	}
+	query = brandService.ApplyFilter(query, brandIDs)
+
 	// Sort options
 	sortBy := c.Query("sort", "name")
 	order := c.Query("order", "asc")
@@ -66,6 +113,7 @@ func GetProducts(c *fiber.Ctx) error {
 
 	return c.JSON(fiber.Map{
 		"products": products,
+		"brands":   brands,
 		"page":     page,
 		"limit":    limit,
 	})
@@ -81,19 +129,59 @@ func GetProductDetails(c *fiber.Ctx) error {
 	}
 
 	var product models.Product
-	if err := config.DB.Preload("Category").Where("slug = ? AND is_active = ?", slug, true).First(&product).Error; err != nil {
+	if err := config.DB.Preload("Category").
+		Preload("Variants", func(db *gorm.DB) *gorm.DB {
+			return db.Where("is_active = ?", true).Order("position ASC, name ASC")
+		}).
+		Preload("BundleItems.Component").Preload("BundleItems.Variant").
+		Preload("Brand").
+		Where("slug = ? AND is_active = ?", slug, true).First(&product).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Product not found",
 		})
 	}
 
-	return c.JSON(product)
+	breadcrumbs, err := services.NewCategoryService().GetBreadcrumbs(product.CategoryID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch product categories",
+		})
+	}
+
+	attributes, err := services.NewAttributeService().GetProductAttributes(product.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch product attributes",
+		})
+	}
+
+	priceTiers, err := services.NewPricingService().PublicTiers(product.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch price tiers",
+		})
+	}
+
+	related, err := services.NewRecommendationService().Related(&product, relatedProductsLimit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch related products",
+		})
+	}
+
+	return c.JSON(ProductDetailsResponse{
+		Product:     product,
+		Breadcrumbs: breadcrumbs,
+		Attributes:  attributes,
+		PriceTiers:  priceTiers,
+		Related:     related,
+	})
 }
 
-// AdminGetCategories returns all categories for admin
+// AdminGetCategories returns all categories for admin as a flat list
 func AdminGetCategories(c *fiber.Ctx) error {
 	var categories []models.Category
-	if err := config.DB.Find(&categories).Error; err != nil {
+	if err := config.DB.Order("position ASC, name ASC").Find(&categories).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to fetch categories",
 		})
@@ -105,8 +193,10 @@ func AdminGetCategories(c *fiber.Ctx) error {
 // AdminCreateCategory creates a new category
 func AdminCreateCategory(c *fiber.Ctx) error {
 	var req struct {
-		Name string `json:"name"`
-		Slug string `json:"slug"`
+		Name     string     `json:"name"`
+		Slug     string     `json:"slug"`
+		ParentID *uuid.UUID `json:"parent_id"`
+		Position int        `json:"position"`
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -129,9 +219,20 @@ func AdminCreateCategory(c *fiber.Ctx) error {
 		})
 	}
 
+	if req.ParentID != nil {
+		var parent models.Category
+		if err := config.DB.First(&parent, "id = ?", *req.ParentID).Error; err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Parent category not found",
+			})
+		}
+	}
+
 	category := models.Category{
-		Name: req.Name,
-		Slug: req.Slug,
+		Name:     req.Name,
+		Slug:     req.Slug,
+		ParentID: req.ParentID,
+		Position: req.Position,
 	}
 
 	if err := config.DB.Create(&category).Error; err != nil {
@@ -153,8 +254,10 @@ func AdminUpdateCategory(c *fiber.Ctx) error {
 	}
 
 	var req struct {
-		Name string `json:"name"`
-		Slug string `json:"slug"`
+		Name     string  `json:"name"`
+		Slug     string  `json:"slug"`
+		ParentID *string `json:"parent_id"` // "" moves the category to the top level
+		Position *int    `json:"position"`
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -177,6 +280,33 @@ func AdminUpdateCategory(c *fiber.Ctx) error {
 	if req.Slug != "" {
 		updates["slug"] = req.Slug
 	}
+	if req.Position != nil {
+		updates["position"] = *req.Position
+	}
+	if req.ParentID != nil {
+		if *req.ParentID == "" {
+			updates["parent_id"] = nil
+		} else {
+			var parent models.Category
+			if err := config.DB.First(&parent, "id = ?", *req.ParentID).Error; err != nil {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Parent category not found",
+				})
+			}
+
+			if err := services.NewCategoryService().ValidateParent(category.ID, parent.ID); err != nil {
+				if errors.Is(err, services.ErrCategoryCycle) {
+					return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+						"error": err.Error(),
+					})
+				}
+				return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+					"error": "Failed to update category",
+				})
+			}
+			updates["parent_id"] = parent.ID
+		}
+	}
 
 	if err := config.DB.Model(&category).Updates(updates).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -210,6 +340,20 @@ func AdminDeleteCategory(c *fiber.Ctx) error {
 		})
 	}
 
+	// Check if category has subcategories
+	var childCount int64
+	if err := config.DB.Model(&models.Category{}).Where("parent_id = ?", id).Count(&childCount).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check category usage",
+		})
+	}
+
+	if childCount > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Cannot delete category with subcategories",
+		})
+	}
+
 	if err := config.DB.Delete(&models.Category{}, "id = ?", id).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to delete category",
@@ -248,40 +392,111 @@ func SearchProducts(c *fiber.Ctx) error {
 
 	
 
-	query := config.DB.Preload("Category")
-
-	// Search by keywords
-	if searchTerm := c.Query("q"); searchTerm != "" {
-		query = query.Where("products.name ILIKE ? OR products.description ILIKE ? OR products.sku ILIKE ?",
-			"%"+searchTerm+"%", "%"+searchTerm+"%", "%"+searchTerm+"%")
+	// Filter by attribute values, e.g. attr.material=steel,brass or attr.weight.max=2
+	attributeFilters, err := parseAttributeFilters(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
 	}
 
-	// Filter by category
+	// Filter by category, including products in its subcategories
+	var categoryIDs []uuid.UUID
 	if categorySlug := c.Query("category"); categorySlug != "" {
-		query = query.Joins("JOIN categories ON categories.id = products.category_id").
-			Where("categories.slug = ?", categorySlug)
+		categoryIDs, err = services.NewCategoryService().GetDescendantIDsBySlug(categorySlug)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to fetch categories",
+			})
+		}
+	}
+
+	// Filter by brand, e.g. brand=dangote,lafarge
+	brandService := services.NewBrandService()
+	brandIDs, err := brandService.ParseFilter(c.Query("brand"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch brands",
+		})
 	}
 
-	// Filter by price range
-	if minPrice := c.Query("min_price"); minPrice != "" {
-		query = query.Where("products.price >= ?", minPrice)
+	attributeService := services.NewAttributeService()
+	searchService := services.NewSearchService()
+	searchTerm := strings.TrimSpace(c.Query("q"))
+	fuzzy := false
+
+	// unbranded builds the product query with every filter applied except the
+	// brand filter and the attribute named by excludeSlug, so facets can count
+	// their own options
+	unbranded := func(excludeSlug string) *gorm.DB {
+		query := config.DB.Model(&models.Product{})
+
+		// Search by keywords
+		if searchTerm != "" {
+			query = searchService.Match(query, searchTerm, fuzzy)
+		}
+
+		if categoryIDs != nil {
+			query = query.Where("products.category_id IN ?", categoryIDs)
+		}
+
+		// Filter by price range
+		if minPrice := c.Query("min_price"); minPrice != "" {
+			query = query.Where("products.price >= ?", minPrice)
+		}
+		if maxPrice := c.Query("max_price"); maxPrice != "" {
+			query = query.Where("products.price <= ?", maxPrice)
+		}
+
+		// Filter by availability
+		if inStock := c.Query("in_stock"); inStock == "true" {
+			query = query.Where("products.stock_quantity > 0")
+		}
+
+		return attributeService.ApplyFilters(query, attributeFilters, excludeSlug)
 	}
-	if maxPrice := c.Query("max_price"); maxPrice != "" {
-		query = query.Where("products.price <= ?", maxPrice)
+	filtered := func(excludeSlug string) *gorm.DB {
+		return brandService.ApplyFilter(unbranded(excludeSlug), brandIDs)
+	}
+
+	// Get total count for pagination
+	var total int64
+	if err := filtered("").Count(&total).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to search products",
+		})
 	}
 
-	// Filter by availability
-	if inStock := c.Query("in_stock"); inStock == "true" {
-		query = query.Where("products.stock_quantity > 0")
+	// Fall back to fuzzy matching when the words match nothing, e.g. misspellings
+	if total == 0 && searchTerm != "" {
+		fuzzy = true
+		if err := filtered("").Count(&total).Error; err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to search products",
+			})
+		}
 	}
 
-	// Sort options
-	sortBy := c.Query("sort", "name")
+	query := filtered("").Preload("Category").Preload("Brand")
+
+	// Sort options, by relevance by default when searching
+	defaultSort := "name"
+	if searchTerm != "" {
+		defaultSort = "relevance"
+	}
+	sortBy := c.Query("sort", defaultSort)
+	if sortBy == "relevance" && searchTerm == "" {
+		sortBy = "name"
+	}
 	order := c.Query("order", "asc")
 	if order != "asc" && order != "desc" {
 		order = "asc"
 	}
-	query = query.Order("products." + sortBy + " " + order)
+	if sortBy == "relevance" {
+		query = searchService.OrderByRelevance(query, searchTerm, fuzzy)
+	} else {
+		query = query.Order("products." + sortBy + " " + order)
+	}
 
 	// Pagination
 	page := c.QueryInt("page", 1)
@@ -294,15 +509,85 @@ func SearchProducts(c *fiber.Ctx) error {
 		})
 	}
 
-	// Get total count for pagination
-	var total int64
-	query.Model(&models.Product{}).Count(&total)
+	results := make([]ProductSearchResult, len(products))
+	for i, product := range products {
+		results[i].Product = product
+	}
+
+	// Highlight matched words; fuzzy matches have no exact words to mark
+	if searchTerm != "" && !fuzzy {
+		productIDs := make([]uuid.UUID, len(products))
+		for i, product := range products {
+			productIDs[i] = product.ID
+		}
+
+		highlights, err := searchService.Highlights(searchTerm, productIDs)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to search products",
+			})
+		}
+
+		for i := range results {
+			if highlight, ok := highlights[results[i].ID]; ok {
+				results[i].Highlight = &highlight
+			}
+		}
+	}
+
+	// Facets cover the attributes of the searched category, its parents and
+	// its subcategories, or of every category when none is given
+	var facetCategoryIDs []uuid.UUID
+	if categoryIDs != nil {
+		facetCategoryIDs, err = services.NewCategoryService().GetAncestorIDs(categoryIDs)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to fetch categories",
+			})
+		}
+	}
+
+	facets, err := attributeService.Facets(facetCategoryIDs, func(excludeSlug string) *gorm.DB {
+		return filtered(excludeSlug).Select("products.id")
+	})
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to count facets",
+		})
+	}
+
+	brands, err := brandService.Facet(unbranded("").Select("products.id"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to count brands",
+		})
+	}
 
 	return c.JSON(fiber.Map{
-		"products": products,
+		"products": results,
+		"facets":   facets,
+		"brands":   brands,
+		"fuzzy":    fuzzy,
 		"total":    total,
 		"page":     page,
 		"limit":    limit,
 		"pages":    (total + int64(limit) - 1) / int64(limit),
 	})
 }
+
+// SuggestProducts returns autocomplete suggestions for products and categories
+func SuggestProducts(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", 8)
+	if limit < 1 || limit > 20 {
+		limit = 8
+	}
+
+	suggestions, err := services.NewSuggestService().Suggest(c.Query("q"), limit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch suggestions",
+		})
+	}
+
+	return c.JSON(suggestions)
+}
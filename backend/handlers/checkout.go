@@ -1,11 +1,15 @@
 package handlers
 
 import (
+	"errors"
+
 	"backend/config"
 	"backend/models"
+	"backend/services"
 
 	"github.com/gofiber/fiber/v2"
 	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
 )
 
 // PlaceOrder handles the checkout process and order placement
@@ -38,7 +42,7 @@ func PlaceOrder(c *fiber.Ctx) error {
 
 	// Get user's cart
 	var cart models.Cart
-	if err := config.DB.Preload("CartItems.Product").Where("user_id = ?", userID).First(&cart).Error; err != nil {
+	if err := config.DB.Preload("CartItems.Product").Preload("CartItems.Variant").Where("user_id = ?", userID).First(&cart).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Cart not found",
 		})
@@ -50,15 +54,28 @@ func PlaceOrder(c *fiber.Ctx) error {
 		})
 	}
 
-	// Calculate total and validate stock
+	// Calculate total at current tier prices and validate stock
+	pricingService := services.NewPricingService()
+	customerGroup := pricingService.CustomerGroup(userID.(string))
+
 	var total float64
-	for _, item := range cart.CartItems {
-		if item.Product.StockQuantity < item.Quantity {
+	unitPrices := make([]float64, len(cart.CartItems))
+	for i, item := range cart.CartItems {
+		stockItem := services.StockItem{Product: &item.Product, Variant: item.Variant}
+		if stockItem.Stock() < item.Quantity {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Insufficient stock for " + item.Product.Name,
+				"error": "Insufficient stock for " + stockItem.DisplayName(),
+			})
+		}
+
+		unitPrice, err := pricingService.UnitPrice(&stockItem, item.Quantity, customerGroup)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to price " + stockItem.DisplayName(),
 			})
 		}
-		total += float64(item.Quantity) * item.Product.Price
+		unitPrices[i] = unitPrice
+		total += item.Quantity * unitPrice
 	}
 
 	// Create order
@@ -84,53 +101,72 @@ func PlaceOrder(c *fiber.Ctx) error {
 		}(),
 	}
 
-	if err := config.DB.Create(&order).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create order",
-		})
-	}
-
-	// Create order items
-	for _, item := range cart.CartItems {
-		orderItem := models.OrderItem{
-			OrderID:   order.ID,
-			ProductID: item.ProductID,
-			Quantity:  item.Quantity,
-			UnitPrice: item.UnitPrice,
-		}
-		if err := config.DB.Create(&orderItem).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to create order item",
-			})
+	// Create the order, take its stock, clear the cart and record the payment
+	// together, so a failure part way leaves no stock taken and no order
+	var payment models.Payment
+	failure := "Failed to create order"
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&order).Error; err != nil {
+			return err
 		}
 
-		// Update product stock
-		if err := config.DB.Model(&item.Product).Update("stock_quantity",
-			item.Product.StockQuantity-item.Quantity).Error; err != nil {
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to update product stock",
-			})
+		// Create order items
+		inventoryService := services.NewInventoryService()
+		for i, item := range cart.CartItems {
+			orderItem := models.OrderItem{
+				OrderID:   order.ID,
+				ProductID: item.ProductID,
+				VariantID: item.VariantID,
+				Quantity:  item.Quantity,
+				UnitPrice: unitPrices[i],
+			}
+
+			// Update product (or variant, or bundle component) stock first so
+			// the item records what a bundle deducted
+			if err := inventoryService.DeductOrderItem(tx, &orderItem); err != nil {
+				failure = "Failed to update product stock"
+				if errors.Is(err, services.ErrInsufficientStock) {
+					failure = "Insufficient stock for " + item.Product.Name
+				}
+				return err
+			}
+
+			if err := tx.Create(&orderItem).Error; err != nil {
+				failure = "Failed to create order item"
+				return err
+			}
 		}
-	}
 
-	// Clear cart
-	if err := config.DB.Where("cart_id = ?", cart.ID).Delete(&models.CartItem{}).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to clear cart",
-		})
-	}
+		// Clear cart
+		if err := tx.Where("cart_id = ?", cart.ID).Delete(&models.CartItem{}).Error; err != nil {
+			failure = "Failed to clear cart"
+			return err
+		}
 
-	// Create payment record
-	payment := models.Payment{
-		OrderID:  order.ID,
-		Provider: req.PaymentMethod,
-		Amount:   total,
-		Status:   models.PaymentStatusPending,
-	}
+		// Create payment record, referenced by the order ID as Paystack
+		// transactions are, so webhooks find it
+		payment = models.Payment{
+			OrderID:   order.ID,
+			UserID:    userUUID,
+			Provider:  req.PaymentMethod,
+			Reference: order.ID.String(),
+			Amount:    total,
+			Status:    models.PaymentStatusPending,
+		}
 
-	if err := config.DB.Create(&payment).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to create payment record",
+		if err := tx.Create(&payment).Error; err != nil {
+			failure = "Failed to create payment record"
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if errors.Is(err, services.ErrInsufficientStock) {
+			status = fiber.StatusBadRequest
+		}
+		return c.Status(status).JSON(fiber.Map{
+			"error": failure,
 		})
 	}
 
@@ -0,0 +1,46 @@
+package handlers
+
+import "backend/models"
+
+// MeasureRequest carries the optional unit-of-measure fields of a product request
+type MeasureRequest struct {
+	Unit             *models.UnitOfMeasure `json:"unit"`
+	UnitSize         *float64              `json:"unit_size"`
+	QuantityStep     *float64              `json:"quantity_step"`
+	PurchaseUnit     *string               `json:"purchase_unit"`
+	PurchaseUnitSize *float64              `json:"purchase_unit_size"`
+}
+
+// defaultMeasure sells by the piece, one at a time
+func defaultMeasure() models.Measure {
+	return models.Measure{
+		Unit:             models.UnitPiece,
+		UnitSize:         1,
+		QuantityStep:     1,
+		PurchaseUnitSize: 1,
+	}
+}
+
+func (r MeasureRequest) changed() bool {
+	return r.Unit != nil || r.UnitSize != nil || r.QuantityStep != nil || r.PurchaseUnit != nil || r.PurchaseUnitSize != nil
+}
+
+// apply overlays the fields present in the request onto an existing measure
+func (r MeasureRequest) apply(measure models.Measure) models.Measure {
+	if r.Unit != nil {
+		measure.Unit = *r.Unit
+	}
+	if r.UnitSize != nil {
+		measure.UnitSize = *r.UnitSize
+	}
+	if r.QuantityStep != nil {
+		measure.QuantityStep = models.RoundQuantity(*r.QuantityStep)
+	}
+	if r.PurchaseUnit != nil {
+		measure.PurchaseUnit = *r.PurchaseUnit
+	}
+	if r.PurchaseUnitSize != nil {
+		measure.PurchaseUnitSize = models.RoundQuantity(*r.PurchaseUnitSize)
+	}
+	return measure
+}
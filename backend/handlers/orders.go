@@ -4,6 +4,7 @@ import (
 	"backend/config"
 	"backend/models"
 	"backend/services"
+	"errors"
 	"fmt"
 	"time"
 
@@ -33,7 +34,7 @@ func CreateOrder(c *fiber.Ctx) error {
 
 	// Get user's cart
 	var cart models.Cart
-	if err := config.DB.Preload("CartItems.Product").
+	if err := config.DB.Preload("CartItems.Product").Preload("CartItems.Variant").
 		Where("user_id = ?", userID).First(&cart).Error; err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Cart is empty",
@@ -46,27 +47,38 @@ func CreateOrder(c *fiber.Ctx) error {
 		})
 	}
 
-	// Calculate total and validate stock
+	// Calculate total at current tier prices and validate stock
+	pricingService := services.NewPricingService()
+	customerGroup := pricingService.CustomerGroup(userID.(string))
+
 	var total float64
 	var orderItems []models.OrderItem
 
 	for _, cartItem := range cart.CartItems {
 		// Check stock availability
-		if cartItem.Product.StockQuantity < cartItem.Quantity {
+		stockItem := services.StockItem{Product: &cartItem.Product, Variant: cartItem.Variant}
+		if stockItem.Stock() < cartItem.Quantity {
 			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Insufficient stock for " + cartItem.Product.Name,
+				"error": "Insufficient stock for " + stockItem.DisplayName(),
 			})
 		}
 
-		// Calculate item total
-		itemTotal := cartItem.UnitPrice * float64(cartItem.Quantity)
+		// Calculate item total, re-pricing in case prices or tiers changed
+		unitPrice, err := pricingService.UnitPrice(&stockItem, cartItem.Quantity, customerGroup)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to price " + stockItem.DisplayName(),
+			})
+		}
+		itemTotal := unitPrice * cartItem.Quantity
 		total += itemTotal
 
 		// Create order item
 		orderItem := models.OrderItem{
 			ProductID: cartItem.ProductID,
+			VariantID: cartItem.VariantID,
 			Quantity:  cartItem.Quantity,
-			UnitPrice: cartItem.UnitPrice,
+			UnitPrice: unitPrice,
 		}
 		orderItems = append(orderItems, orderItem)
 	}
@@ -99,25 +111,30 @@ func CreateOrder(c *fiber.Ctx) error {
 		})
 	}
 
-	// Create order items
-	for i := range orderItems {
-		orderItems[i].OrderID = order.ID
-		if err := tx.Create(&orderItems[i]).Error; err != nil {
+	// Update product (or variant, or bundle component) stock, then create
+	// the order items, which record what bundles deducted
+	inventoryService := services.NewInventoryService()
+	for i, cartItem := range cart.CartItems {
+		if err := inventoryService.DeductOrderItem(tx, &orderItems[i]); err != nil {
 			tx.Rollback()
+			if errors.Is(err, services.ErrInsufficientStock) {
+				return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+					"error": "Insufficient stock for " + cartItem.Product.Name,
+				})
+			}
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to create order items",
+				"error": "Failed to update product stock",
 			})
 		}
 	}
 
-	// Update product stock
-	for _, cartItem := range cart.CartItems {
-		if err := tx.Model(&models.Product{}).
-			Where("id = ?", cartItem.ProductID).
-			Update("stock_quantity", cartItem.Product.StockQuantity-cartItem.Quantity).Error; err != nil {
+	// Create order items
+	for i := range orderItems {
+		orderItems[i].OrderID = order.ID
+		if err := tx.Create(&orderItems[i]).Error; err != nil {
 			tx.Rollback()
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-				"error": "Failed to update product stock",
+				"error": "Failed to create order items",
 			})
 		}
 	}
@@ -169,7 +186,7 @@ func GetUserOrders(c *fiber.Ctx) error {
 	}
 
 	var orders []models.Order
-	if err := config.DB.Preload("OrderItems.Product.Category").
+	if err := config.DB.Preload("OrderItems.Product.Category").Preload("OrderItems.Variant").
 		Where("user_id = ?", userID).
 		Order("placed_at DESC").
 		Find(&orders).Error; err != nil {
@@ -198,7 +215,7 @@ func GetOrderDetails(c *fiber.Ctx) error {
 	}
 
 	var order models.Order
-	if err := config.DB.Preload("OrderItems.Product.Category").
+	if err := config.DB.Preload("OrderItems.Product.Category").Preload("OrderItems.Variant").
 		Where("id = ? AND user_id = ?", orderID, userID).First(&order).Error; err != nil {
 		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
 			"error": "Order not found",
@@ -212,7 +229,7 @@ func GetOrderDetails(c *fiber.Ctx) error {
 func AdminGetOrders(c *fiber.Ctx) error {
 	var orders []models.Order
 
-	query := config.DB.Preload("OrderItems.Product.Category").Preload("User")
+	query := config.DB.Preload("OrderItems.Product.Category").Preload("OrderItems.Variant").Preload("User")
 
 	// Filter by status
 	if status := c.Query("status"); status != "" {
@@ -345,10 +362,10 @@ func CancelOrder(c *fiber.Ctx) error {
 		})
 	}
 
-	// Restore product stock
-	for _, item := range order.OrderItems {
-		if err := config.DB.Model(&item.Product).Update("stock_quantity",
-			item.Product.StockQuantity+item.Quantity).Error; err != nil {
+	// Restore product (or variant) stock
+	inventoryService := services.NewInventoryService()
+	for i := range order.OrderItems {
+		if err := inventoryService.RestoreOrderItem(config.DB, &order.OrderItems[i]); err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "Failed to restore product stock",
 			})
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"errors"
+
+	"backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type OTPLoginRequest struct {
+	Phone string `json:"phone"`
+	Code  string `json:"code"`
+}
+
+// RequestLoginOTP texts a one-time sign-in code to a customer's phone
+func RequestLoginOTP(c *fiber.Ctx) error {
+	var req OTPLoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if req.Phone == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Phone number is required",
+		})
+	}
+
+	retryAfter, err := services.NewOTPLoginService().RequestCode(req.Phone)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrOTPInvalidPhone):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid phone number. Use international format, e.g. +254712345678",
+			})
+		case errors.Is(err, services.ErrOTPResendTooSoon), errors.Is(err, services.ErrOTPTooManyRequests):
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error":       err.Error(),
+				"retry_after": int(retryAfter.Seconds()) + 1,
+			})
+		case errors.Is(err, services.ErrOTPUnavailable):
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		default:
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to send sign-in code",
+			})
+		}
+	}
+
+	// Same response whether or not the number is registered
+	return c.JSON(fiber.Map{
+		"message": "If an account uses this number, a sign-in code has been sent",
+	})
+}
+
+// VerifyLoginOTP signs a customer in with the code texted to their phone
+func VerifyLoginOTP(c *fiber.Ctx) error {
+	var req OTPLoginRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if req.Phone == "" || req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Phone number and code are required",
+		})
+	}
+
+	user, err := services.NewOTPLoginService().VerifyCode(req.Phone, req.Code)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrOTPTooManyAttempts):
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		case errors.Is(err, services.ErrOTPUnavailable):
+			return c.Status(fiber.StatusServiceUnavailable).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		default:
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid or expired code",
+			})
+		}
+	}
+
+	if user.IsLocked() {
+		return c.Status(fiber.StatusLocked).JSON(fiber.Map{
+			"error":        "Account is temporarily locked due to failed login attempts",
+			"locked_until": user.LockedUntil,
+		})
+	}
+
+	return completeLogin(c, user)
+}
@@ -7,7 +7,6 @@ import (
 	"fmt"
 
 	"github.com/gofiber/fiber/v2"
-	uuid "github.com/satori/go.uuid"
 )
 
 // InitiatePayment starts a payment process
@@ -51,10 +50,12 @@ func InitiatePayment(c *fiber.Ctx) error {
 	if err := config.DB.Where("order_id = ?", req.OrderID).First(&payment).Error; err != nil {
 		// Create new payment
 		payment = models.Payment{
-			OrderID:  uuid.FromStringOrNil(req.OrderID),
-			Provider: req.PaymentMethod,
-			Amount:   req.Amount,
-			Status:   models.PaymentStatusPending,
+			OrderID:   order.ID,
+			UserID:    *order.UserID,
+			Provider:  req.PaymentMethod,
+			Reference: order.ID.String(),
+			Amount:    req.Amount,
+			Status:    models.PaymentStatusPending,
 		}
 		if err := config.DB.Create(&payment).Error; err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
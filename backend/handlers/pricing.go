@@ -0,0 +1,367 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"backend/config"
+	"backend/models"
+	"backend/services"
+
+	"github.com/gofiber/fiber/v2"
+	uuid "github.com/satori/go.uuid"
+)
+
+type PriceTierRequest struct {
+	VariantID     *string  `json:"variant_id"`
+	CustomerGroup *string  `json:"customer_group"`
+	MinQuantity   *float64 `json:"min_quantity"`
+	UnitPrice     *float64 `json:"unit_price"`
+}
+
+// AdminGetPriceTiers lists every price tier of a product, including group-only tiers
+func AdminGetPriceTiers(c *fiber.Ctx) error {
+	var tiers []models.PriceTier
+	if err := config.DB.Where("product_id = ?", c.Params("id")).
+		Order("variant_id ASC, customer_group ASC, min_quantity ASC").
+		Find(&tiers).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch price tiers",
+		})
+	}
+
+	return c.JSON(tiers)
+}
+
+// AdminCreatePriceTier adds a quantity break to a product or one of its variants
+func AdminCreatePriceTier(c *fiber.Ctx) error {
+	var product models.Product
+	if err := config.DB.First(&product, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Product not found",
+		})
+	}
+
+	var req PriceTierRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if req.MinQuantity == nil || req.UnitPrice == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "min_quantity and unit_price are required",
+		})
+	}
+
+	tier := models.PriceTier{
+		ProductID:   product.ID,
+		MinQuantity: *req.MinQuantity,
+		UnitPrice:   *req.UnitPrice,
+	}
+	if req.VariantID != nil && *req.VariantID != "" {
+		variantID, err := uuid.FromString(*req.VariantID)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid variant ID",
+			})
+		}
+		tier.VariantID = &variantID
+	}
+	if req.CustomerGroup != nil && *req.CustomerGroup != "" {
+		tier.CustomerGroup = req.CustomerGroup
+	}
+
+	if err := services.NewPricingService().ValidateTier(&tier); err != nil {
+		return pricingError(c, err)
+	}
+
+	if err := config.DB.Create(&tier).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create price tier",
+		})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(tier)
+}
+
+// AdminUpdatePriceTier changes a tier's quantity, price or customer group
+func AdminUpdatePriceTier(c *fiber.Ctx) error {
+	var tier models.PriceTier
+	if err := config.DB.First(&tier, "id = ? AND product_id = ?", c.Params("tierId"), c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Price tier not found",
+		})
+	}
+
+	var req PriceTierRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	updates := map[string]interface{}{}
+
+	if req.MinQuantity != nil {
+		tier.MinQuantity = *req.MinQuantity
+		updates["min_quantity"] = *req.MinQuantity
+	}
+	if req.UnitPrice != nil {
+		tier.UnitPrice = *req.UnitPrice
+		updates["unit_price"] = *req.UnitPrice
+	}
+	if req.CustomerGroup != nil {
+		// An empty group opens the tier to every customer
+		if *req.CustomerGroup == "" {
+			tier.CustomerGroup = nil
+		} else {
+			tier.CustomerGroup = req.CustomerGroup
+		}
+		updates["customer_group"] = tier.CustomerGroup
+	}
+
+	if err := services.NewPricingService().ValidateTier(&tier); err != nil {
+		return pricingError(c, err)
+	}
+
+	if err := config.DB.Model(&tier).Updates(updates).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update price tier",
+		})
+	}
+
+	return c.JSON(tier)
+}
+
+// AdminDeletePriceTier removes a price tier
+func AdminDeletePriceTier(c *fiber.Ctx) error {
+	result := config.DB.Where("id = ? AND product_id = ?", c.Params("tierId"), c.Params("id")).
+		Delete(&models.PriceTier{})
+	if result.Error != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete price tier",
+		})
+	}
+	if result.RowsAffected == 0 {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Price tier not found",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Price tier deleted successfully",
+	})
+}
+
+// AdminUpdateUserCustomerGroup places a customer in a pricing group, or
+// removes them from it with an empty group
+func AdminUpdateUserCustomerGroup(c *fiber.Ctx) error {
+	var req struct {
+		CustomerGroup string `json:"customer_group"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var group *string
+	if req.CustomerGroup != "" {
+		if !services.ValidCustomerGroup(req.CustomerGroup) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": services.ErrInvalidCustomerGroup.Error(),
+			})
+		}
+		group = &req.CustomerGroup
+	}
+
+	var user models.User
+	if err := config.DB.First(&user, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	if err := config.DB.Model(&user).Update("customer_group", group).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update customer group",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":        "Customer group updated successfully",
+		"customer_group": group,
+	})
+}
+
+func pricingError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrPriceTierExists):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrVariantNotFound):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Variant not found for this product"})
+	case errors.Is(err, services.ErrInvalidPriceTier), errors.Is(err, services.ErrInvalidCustomerGroup),
+		errors.Is(err, services.ErrInvalidSale):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save pricing"})
+	}
+}
+
+type SaleRequest struct {
+	SalePrice *float64   `json:"sale_price"`
+	StartsAt  *time.Time `json:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at"`
+}
+
+// AdminSetProductSale schedules a sale price for a product. Omitting
+// starts_at starts the sale now; omitting ends_at runs it until removed.
+func AdminSetProductSale(c *fiber.Ctx) error {
+	var product models.Product
+	if err := config.DB.First(&product, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Product not found",
+		})
+	}
+
+	var req SaleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	sale := models.SalePricing{SalePrice: req.SalePrice, SaleStartsAt: req.StartsAt, SaleEndsAt: req.EndsAt}
+	if err := services.NewPricingService().ValidateSale(product.Price, sale); err != nil {
+		return pricingError(c, err)
+	}
+
+	if err := config.DB.Model(&product).Updates(saleColumns(sale)).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to schedule sale",
+		})
+	}
+
+	recordPriceChange(c, models.PriceChange{ProductID: product.ID, Price: product.Price, SalePricing: sale})
+
+	config.DB.First(&product, "id = ?", product.ID)
+	return c.JSON(product)
+}
+
+// AdminDeleteProductSale ends a product's sale, or cancels a scheduled one
+func AdminDeleteProductSale(c *fiber.Ctx) error {
+	var product models.Product
+	if err := config.DB.First(&product, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Product not found",
+		})
+	}
+
+	if err := config.DB.Model(&product).Updates(saleColumns(models.SalePricing{})).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to remove sale",
+		})
+	}
+
+	recordPriceChange(c, models.PriceChange{ProductID: product.ID, Price: product.Price})
+
+	return c.JSON(fiber.Map{
+		"message": "Sale removed successfully",
+	})
+}
+
+// AdminSetVariantSale schedules a sale price for a single variant
+func AdminSetVariantSale(c *fiber.Ctx) error {
+	var variant models.ProductVariant
+	if err := config.DB.First(&variant, "id = ? AND product_id = ?", c.Params("variantId"), c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Variant not found",
+		})
+	}
+
+	var req SaleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	sale := models.SalePricing{SalePrice: req.SalePrice, SaleStartsAt: req.StartsAt, SaleEndsAt: req.EndsAt}
+	if err := services.NewPricingService().ValidateSale(variant.Price, sale); err != nil {
+		return pricingError(c, err)
+	}
+
+	if err := config.DB.Model(&variant).Updates(saleColumns(sale)).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to schedule sale",
+		})
+	}
+
+	recordPriceChange(c, models.PriceChange{ProductID: variant.ProductID, VariantID: &variant.ID, Price: variant.Price, SalePricing: sale})
+
+	config.DB.First(&variant, "id = ?", variant.ID)
+	return c.JSON(variant)
+}
+
+// AdminDeleteVariantSale ends a variant's sale, or cancels a scheduled one
+func AdminDeleteVariantSale(c *fiber.Ctx) error {
+	var variant models.ProductVariant
+	if err := config.DB.First(&variant, "id = ? AND product_id = ?", c.Params("variantId"), c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Variant not found",
+		})
+	}
+
+	if err := config.DB.Model(&variant).Updates(saleColumns(models.SalePricing{})).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to remove sale",
+		})
+	}
+
+	recordPriceChange(c, models.PriceChange{ProductID: variant.ProductID, VariantID: &variant.ID, Price: variant.Price})
+
+	return c.JSON(fiber.Map{
+		"message": "Sale removed successfully",
+	})
+}
+
+// AdminGetPriceHistory lists a product's and its variants' price changes, newest first
+func AdminGetPriceHistory(c *fiber.Ctx) error {
+	changes, err := services.NewPricingService().PriceHistory(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch price history",
+		})
+	}
+
+	return c.JSON(changes)
+}
+
+// saleColumns maps a sale to the columns it is stored in; a zero sale clears them
+func saleColumns(sale models.SalePricing) map[string]interface{} {
+	return map[string]interface{}{
+		"sale_price":     sale.SalePrice,
+		"sale_starts_at": sale.SaleStartsAt,
+		"sale_ends_at":   sale.SaleEndsAt,
+	}
+}
+
+// recordPriceChange credits a price snapshot to the admin or API key making
+// the request. A failure is logged rather than undoing the price change.
+func recordPriceChange(c *fiber.Ctx, change models.PriceChange) {
+	if key, ok := c.Locals("api_key").(*models.APIKey); ok {
+		change.APIKeyID = &key.ID
+	} else if userID, ok := c.Locals("user_id").(string); ok {
+		if id, err := uuid.FromString(userID); err == nil {
+			change.ChangedByID = &id
+		}
+	}
+
+	if err := services.NewPricingService().RecordPriceChange(config.DB, change); err != nil {
+		fmt.Printf("Failed to record price change: %v\n", err)
+	}
+}
@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// ExportUserData returns the current user's personal data as JSON, or as a
+// ZIP archive of JSON files with ?format=zip
+func ExportUserData(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if user == nil {
+		return err
+	}
+
+	format := c.Query("format", "json")
+	if format != "json" && format != "zip" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid format. Use 'json' or 'zip'",
+		})
+	}
+
+	privacyService := services.NewPrivacyService()
+	export, err := privacyService.Export(user.ID.String())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to export data",
+		})
+	}
+
+	filename := fmt.Sprintf("hardware-store-data-%s", export.ExportedAt.Format("20060102"))
+
+	if format == "zip" {
+		archive, err := privacyService.ExportZip(export)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to export data",
+			})
+		}
+
+		c.Set(fiber.HeaderContentType, "application/zip")
+		c.Attachment(filename + ".zip")
+		return c.Send(archive)
+	}
+
+	c.Attachment(filename + ".json")
+	return c.JSON(export)
+}
+
+// EraseAccount anonymizes the current user's personal data and closes the
+// account. Orders and payments are kept for accounting.
+func EraseAccount(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if user == nil {
+		return err
+	}
+
+	var req struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if user.Role != models.RoleCustomer {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Staff accounts must be removed by an administrator",
+		})
+	}
+
+	if !utils.VerifyPassword(req.Password, user.PasswordHash) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid password",
+		})
+	}
+
+	if user.TwoFactorEnabled && !services.NewTwoFactorService().VerifyCode(user, req.Code) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid two-factor code",
+		})
+	}
+
+	if err := services.NewPrivacyService().Erase(user); err != nil {
+		if errors.Is(err, services.ErrErasureBlocked) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Your account has open orders or service requests. Please wait until they are completed or cancel them first",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to erase account",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Your account has been closed and your personal data erased",
+	})
+}
@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"backend/config"
+	"backend/models"
+	"backend/services"
+
+	"github.com/gofiber/fiber/v2"
+	uuid "github.com/satori/go.uuid"
+)
+
+// maxImportFileSize limits uploaded CSV files to 10MB
+const maxImportFileSize = 10 * 1024 * 1024
+
+// AdminImportProducts starts a background import of a products CSV file.
+// With dry_run=true rows are only validated and counted.
+func AdminImportProducts(c *fiber.Ctx) error {
+	file, err := c.FormFile("file")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "No file uploaded",
+		})
+	}
+
+	if file.Size > maxImportFileSize {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "File size too large. Maximum size is 10MB",
+		})
+	}
+
+	src, err := file.Open()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to open file",
+		})
+	}
+	defer src.Close()
+
+	csvService := services.NewProductCSVService()
+
+	rows, err := csvService.ParseImport(src)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var createdByID *uuid.UUID
+	if _, isAPIKey := c.Locals("api_key").(*models.APIKey); !isAPIKey {
+		if userID, ok := c.Locals("user_id").(string); ok {
+			if id, err := uuid.FromString(userID); err == nil {
+				createdByID = &id
+			}
+		}
+	}
+
+	dryRun := c.FormValue("dry_run") == "true"
+
+	job, err := csvService.StartImport(rows, file.Filename, dryRun, createdByID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to start import",
+		})
+	}
+
+	return c.Status(fiber.StatusAccepted).JSON(job)
+}
+
+// AdminGetProductImports lists recent product import jobs without their row errors
+func AdminGetProductImports(c *fiber.Ctx) error {
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 20)
+	offset := (page - 1) * limit
+
+	var jobs []models.ProductImportJob
+	if err := config.DB.Omit("row_errors").
+		Order("created_at DESC").
+		Offset(offset).Limit(limit).
+		Find(&jobs).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch import jobs",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"jobs":  jobs,
+		"page":  page,
+		"limit": limit,
+	})
+}
+
+// AdminGetProductImport returns the progress and row errors of an import job
+func AdminGetProductImport(c *fiber.Ctx) error {
+	var job models.ProductImportJob
+	if err := config.DB.Where("id = ?", c.Params("id")).First(&job).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Import job not found",
+		})
+	}
+
+	return c.JSON(job)
+}
+
+// AdminExportProducts downloads every product matching the admin product
+// list filters as CSV, in the layout the import accepts
+func AdminExportProducts(c *fiber.Ctx) error {
+	var products []models.Product
+	if err := adminProductsQuery(c).Find(&products).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch products",
+		})
+	}
+
+	var buf bytes.Buffer
+	if err := services.NewProductCSVService().WriteExport(&buf, products); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to export products",
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "text/csv; charset=utf-8")
+	c.Set(fiber.HeaderContentDisposition, fmt.Sprintf(`attachment; filename="products-%s.csv"`, time.Now().Format("20060102-150405")))
+	return c.Send(buf.Bytes())
+}
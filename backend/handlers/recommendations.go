@@ -0,0 +1,93 @@
+package handlers
+
+import (
+	"errors"
+
+	"backend/config"
+	"backend/models"
+	"backend/services"
+
+	"github.com/gofiber/fiber/v2"
+	uuid "github.com/satori/go.uuid"
+)
+
+// relatedProductsLimit is how many related products or cart recommendations are shown
+const relatedProductsLimit = 8
+
+// AdminGetRelatedProducts returns a product's computed related products,
+// its overrides and the resulting list shoppers see
+func AdminGetRelatedProducts(c *fiber.Ctx) error {
+	var product models.Product
+	if err := config.DB.First(&product, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Product not found",
+		})
+	}
+
+	view, err := services.NewRecommendationService().GetAdminView(&product, relatedProductsLimit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch related products",
+		})
+	}
+
+	return c.JSON(view)
+}
+
+// AdminSetRelatedProducts replaces a product's curated overrides: pinned
+// products are shown first in the order given, hidden ones never
+func AdminSetRelatedProducts(c *fiber.Ctx) error {
+	var product models.Product
+	if err := config.DB.First(&product, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Product not found",
+		})
+	}
+
+	var req struct {
+		Pinned []uuid.UUID `json:"pinned"`
+		Hidden []uuid.UUID `json:"hidden"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	recommendations := services.NewRecommendationService()
+
+	if err := recommendations.SetOverrides(product.ID, req.Pinned, req.Hidden); err != nil {
+		if errors.Is(err, services.ErrInvalidRelatedOverride) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to save related products",
+		})
+	}
+
+	view, err := recommendations.GetAdminView(&product, relatedProductsLimit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch related products",
+		})
+	}
+
+	return c.JSON(view)
+}
+
+// AdminRefreshRelatedProducts recomputes co-purchase statistics now rather
+// than waiting for the next scheduled refresh
+func AdminRefreshRelatedProducts(c *fiber.Ctx) error {
+	count, err := services.NewRecommendationService().Refresh()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to refresh related products",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Related products refreshed",
+		"pairs":   count,
+	})
+}
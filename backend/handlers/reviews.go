@@ -0,0 +1,333 @@
+package handlers
+
+import (
+	"errors"
+
+	"backend/config"
+	"backend/models"
+	"backend/services"
+
+	"github.com/gofiber/fiber/v2"
+	uuid "github.com/satori/go.uuid"
+)
+
+type ReviewRequest struct {
+	ProductID string `json:"product_id"`
+	services.ReviewContent
+}
+
+// GetProductReviews returns a product's approved reviews with its rating breakdown
+func GetProductReviews(c *fiber.Ctx) error {
+	var product models.Product
+	if err := config.DB.Where("slug = ? AND is_active = ?", c.Params("slug"), true).First(&product).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Product not found",
+		})
+	}
+
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 10)
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+	offset := (page - 1) * limit
+
+	reviewService := services.NewReviewService()
+
+	reviews, total, err := reviewService.ProductReviews(product.ID, c.Query("sort"), limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch reviews",
+		})
+	}
+
+	breakdown, err := reviewService.RatingBreakdown(product.ID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch ratings",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"reviews":          reviews,
+		"rating_average":   product.RatingAverage,
+		"rating_count":     product.RatingCount,
+		"rating_breakdown": breakdown,
+		"total":            total,
+		"page":             page,
+		"limit":            limit,
+	})
+}
+
+// GetUserReviews returns the user's own reviews, including pending and rejected ones
+func GetUserReviews(c *fiber.Ctx) error {
+	userID := c.Locals("user_id")
+	if userID == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User not authenticated",
+		})
+	}
+
+	reviews, err := services.NewReviewService().GetUserReviews(userID.(string))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch reviews",
+		})
+	}
+
+	return c.JSON(reviews)
+}
+
+// CreateReview submits a review of a delivered product for moderation
+func CreateReview(c *fiber.Ctx) error {
+	userID := c.Locals("user_id")
+	if userID == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User not authenticated",
+		})
+	}
+
+	userUUID := uuid.FromStringOrNil(userID.(string))
+	if userUUID == uuid.Nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid user ID",
+		})
+	}
+
+	var req ReviewRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	productID, err := uuid.FromString(req.ProductID)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid product ID",
+		})
+	}
+
+	review, err := services.NewReviewService().Create(userUUID, productID, req.ReviewContent)
+	if err != nil {
+		return reviewError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(review)
+}
+
+// UpdateReview edits the user's review, which then awaits moderation again
+func UpdateReview(c *fiber.Ctx) error {
+	userID := c.Locals("user_id")
+	if userID == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User not authenticated",
+		})
+	}
+
+	reviewService := services.NewReviewService()
+
+	review, err := reviewService.GetUserReview(userID.(string), c.Params("id"))
+	if err != nil {
+		return reviewError(c, err)
+	}
+
+	var req services.ReviewContent
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if err := reviewService.Update(review, req); err != nil {
+		return reviewError(c, err)
+	}
+
+	return c.JSON(review)
+}
+
+// DeleteReview deletes the user's review
+func DeleteReview(c *fiber.Ctx) error {
+	userID := c.Locals("user_id")
+	if userID == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User not authenticated",
+		})
+	}
+
+	reviewService := services.NewReviewService()
+
+	review, err := reviewService.GetUserReview(userID.(string), c.Params("id"))
+	if err != nil {
+		return reviewError(c, err)
+	}
+
+	if err := reviewService.Delete(review); err != nil {
+		return reviewError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Review deleted successfully",
+	})
+}
+
+// AddReviewPhotos uploads photos sent as "photos" to the user's review
+func AddReviewPhotos(c *fiber.Ctx) error {
+	userID := c.Locals("user_id")
+	if userID == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User not authenticated",
+		})
+	}
+
+	reviewService := services.NewReviewService()
+
+	review, err := reviewService.GetUserReview(userID.(string), c.Params("id"))
+	if err != nil {
+		return reviewError(c, err)
+	}
+
+	form, err := c.MultipartForm()
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Failed to parse form",
+		})
+	}
+
+	files := form.File["photos"]
+	if len(files) == 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "No photos uploaded",
+		})
+	}
+
+	for _, file := range files {
+		if file.Size > 10*1024*1024 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "File size too large. Maximum size is 10MB",
+			})
+		}
+
+		src, err := file.Open()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to open file",
+			})
+		}
+
+		_, err = reviewService.AddPhoto(review, src, file.Filename)
+		src.Close()
+		if err != nil {
+			return reviewError(c, err)
+		}
+	}
+
+	return c.JSON(review)
+}
+
+// DeleteReviewPhoto removes a photo from the user's review
+func DeleteReviewPhoto(c *fiber.Ctx) error {
+	userID := c.Locals("user_id")
+	if userID == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User not authenticated",
+		})
+	}
+
+	reviewService := services.NewReviewService()
+
+	review, err := reviewService.GetUserReview(userID.(string), c.Params("id"))
+	if err != nil {
+		return reviewError(c, err)
+	}
+
+	if err := reviewService.RemovePhoto(review, c.Query("public_id")); err != nil {
+		return reviewError(c, err)
+	}
+
+	return c.JSON(review)
+}
+
+// AdminGetReviews returns the moderation queue, pending reviews by default
+func AdminGetReviews(c *fiber.Ctx) error {
+	status := models.ReviewStatus(c.Query("status", string(models.ReviewStatusPending)))
+
+	page := c.QueryInt("page", 1)
+	limit := c.QueryInt("limit", 20)
+	offset := (page - 1) * limit
+
+	reviews, total, err := services.NewReviewService().ModerationQueue(status, limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch reviews",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"reviews": reviews,
+		"total":   total,
+		"page":    page,
+		"limit":   limit,
+	})
+}
+
+// AdminModerateReview approves or rejects a review
+func AdminModerateReview(c *fiber.Ctx) error {
+	var req struct {
+		Status models.ReviewStatus `json:"status"`
+		Note   string              `json:"note"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	var moderatorID *uuid.UUID
+	if userID, ok := c.Locals("user_id").(string); ok {
+		if id, err := uuid.FromString(userID); err == nil {
+			moderatorID = &id
+		}
+	}
+
+	review, err := services.NewReviewService().Moderate(c.Params("id"), req.Status, req.Note, moderatorID)
+	if err != nil {
+		return reviewError(c, err)
+	}
+
+	return c.JSON(review)
+}
+
+// AdminDeleteReview removes a review, e.g. spam that should not stay on record
+func AdminDeleteReview(c *fiber.Ctx) error {
+	var review models.Review
+	if err := config.DB.Where("id = ?", c.Params("id")).First(&review).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Review not found",
+		})
+	}
+
+	if err := services.NewReviewService().Delete(&review); err != nil {
+		return reviewError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Review deleted successfully",
+	})
+}
+
+func reviewError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrReviewNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrReviewNotAllowed):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrAlreadyReviewed):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrInvalidReview), errors.Is(err, services.ErrTooManyPhotos):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save review"})
+	}
+}
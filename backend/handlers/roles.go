@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"errors"
+
+	"backend/models"
+	"backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type RoleResponse struct {
+	models.Role
+	UserCount int64 `json:"user_count"`
+}
+
+type RoleRequest struct {
+	Name        string              `json:"name"`
+	Description *string             `json:"description"`
+	Permissions []models.Permission `json:"permissions"`
+}
+
+// AdminGetPermissions lists every permission that can be granted to a role
+func AdminGetPermissions(c *fiber.Ctx) error {
+	return c.JSON(fiber.Map{
+		"permissions": models.AllPermissions,
+	})
+}
+
+// AdminGetRoles lists roles with their permissions and member counts
+func AdminGetRoles(c *fiber.Ctx) error {
+	roles, members, err := services.NewRoleService().ListRoles()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch roles",
+		})
+	}
+
+	response := make([]RoleResponse, 0, len(roles))
+	for _, role := range roles {
+		response = append(response, RoleResponse{
+			Role:      role,
+			UserCount: members[role.Name],
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"roles": response,
+	})
+}
+
+// AdminCreateRole creates a custom role
+func AdminCreateRole(c *fiber.Ctx) error {
+	var req RoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	if req.Name == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Role name is required",
+		})
+	}
+
+	description := ""
+	if req.Description != nil {
+		description = *req.Description
+	}
+
+	role, err := services.NewRoleService().CreateRole(req.Name, description, req.Permissions)
+	if err != nil {
+		return roleError(c, err)
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+		"message": "Role created successfully",
+		"role":    role,
+	})
+}
+
+// AdminUpdateRole updates a role's description or permissions
+func AdminUpdateRole(c *fiber.Ctx) error {
+	roleID := c.Params("id")
+	if roleID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Role ID is required",
+		})
+	}
+
+	var req RoleRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	role, err := services.NewRoleService().UpdateRole(roleID, req.Description, req.Permissions)
+	if err != nil {
+		return roleError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Role updated successfully",
+		"role":    role,
+	})
+}
+
+// AdminDeleteRole deletes a custom role that has no members
+func AdminDeleteRole(c *fiber.Ctx) error {
+	roleID := c.Params("id")
+	if roleID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Role ID is required",
+		})
+	}
+
+	if err := services.NewRoleService().DeleteRole(roleID); err != nil {
+		return roleError(c, err)
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Role deleted successfully",
+	})
+}
+
+// roleError maps role service errors to HTTP responses
+func roleError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrRoleNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Role not found"})
+	case errors.Is(err, services.ErrRoleExists):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Role already exists"})
+	case errors.Is(err, services.ErrRoleInUse):
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Role is still assigned to users"})
+	case errors.Is(err, services.ErrSystemRole):
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case errors.Is(err, services.ErrUnknownPermission), errors.Is(err, services.ErrInvalidRoleName):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to save role"})
+	}
+}
@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"fmt"
+
+	"backend/config"
+	"backend/models"
+	"backend/services"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/gorm"
+)
+
+// Sitemap serves the storefront sitemap, or a sitemap index pointing at
+// /sitemap-<n>.xml pages once the catalog outgrows a single file
+func Sitemap(c *fiber.Ctx) error {
+	seo := services.NewSEOService()
+
+	pages, err := seo.SitemapPages()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to build sitemap",
+		})
+	}
+
+	if pages > 1 {
+		index, err := seo.SitemapIndex(pages)
+		if err != nil {
+			fmt.Printf("Failed to build sitemap index: %v\n", err)
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to build sitemap",
+			})
+		}
+		return sendXML(c, index)
+	}
+
+	set, err := seo.SitemapPage(1)
+	if err != nil {
+		fmt.Printf("Failed to build sitemap: %v\n", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to build sitemap",
+		})
+	}
+
+	return sendXML(c, set)
+}
+
+// SitemapPage serves one page of a sitemap index
+func SitemapPage(c *fiber.Ctx) error {
+	seo := services.NewSEOService()
+
+	pages, err := seo.SitemapPages()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to build sitemap",
+		})
+	}
+
+	page, err := c.ParamsInt("page")
+	if err != nil || page < 1 || page > pages {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Sitemap not found",
+		})
+	}
+
+	set, err := seo.SitemapPage(page)
+	if err != nil {
+		fmt.Printf("Failed to build sitemap: %v\n", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to build sitemap",
+		})
+	}
+
+	return sendXML(c, set)
+}
+
+// GetProductStructuredData returns a product as schema.org JSON-LD for the
+// storefront to embed in its product page
+func GetProductStructuredData(c *fiber.Ctx) error {
+	var product models.Product
+	if err := config.DB.Preload("Category").Preload("Brand").
+		Preload("Variants", func(db *gorm.DB) *gorm.DB {
+			return db.Where("is_active = ?", true).Order("position ASC, name ASC")
+		}).
+		Where("slug = ? AND is_active = ?", c.Params("slug"), true).First(&product).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Product not found",
+		})
+	}
+
+	return c.JSON(services.NewSEOService().ProductJSONLD(&product), "application/ld+json")
+}
+
+// sendXML writes an XML document, cacheable for an hour since crawlers
+// fetch sitemaps far more often than the catalog changes
+func sendXML(c *fiber.Ctx, document interface{}) error {
+	body, err := xml.Marshal(document)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to build sitemap",
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationXMLCharsetUTF8)
+	c.Set(fiber.HeaderCacheControl, "public, max-age=3600")
+	return c.Send(append([]byte(xml.Header), body...))
+}
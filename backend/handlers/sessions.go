@@ -0,0 +1,115 @@
+package handlers
+
+import (
+	"backend/config"
+	"backend/models"
+	"backend/services"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type SessionResponse struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+// GetUserSessions lists the devices the current user is signed in on
+func GetUserSessions(c *fiber.Ctx) error {
+	userID := c.Locals("user_id")
+	if userID == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User not authenticated",
+		})
+	}
+
+	sessions, err := services.NewSessionService().GetUserSessions(userID.(string))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch sessions",
+		})
+	}
+
+	currentSessionID := c.Locals("session_id")
+	response := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, SessionResponse{
+			ID:         session.ID.String(),
+			UserAgent:  session.UserAgent,
+			IPAddress:  session.IPAddress,
+			CreatedAt:  session.CreatedAt,
+			LastSeenAt: session.LastSeenAt,
+			ExpiresAt:  session.ExpiresAt,
+			Current:    session.ID.String() == currentSessionID,
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"sessions": response,
+		"count":    len(response),
+	})
+}
+
+// RevokeUserSession signs the current user out of a single device
+func RevokeUserSession(c *fiber.Ctx) error {
+	userID := c.Locals("user_id")
+	if userID == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User not authenticated",
+		})
+	}
+
+	sessionID := c.Params("id")
+	if sessionID == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Session ID is required",
+		})
+	}
+
+	// Find session and verify ownership
+	var session models.Session
+	if err := config.DB.Where("id = ? AND user_id = ? AND revoked_at IS NULL", sessionID, userID).First(&session).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Session not found",
+		})
+	}
+
+	if err := services.NewSessionService().RevokeSession(session.ID.String(), models.SessionRevokedByUser); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke session",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Session revoked successfully",
+		"current": session.ID.String() == c.Locals("session_id"),
+	})
+}
+
+// RevokeOtherUserSessions signs the current user out of every other device
+func RevokeOtherUserSessions(c *fiber.Ctx) error {
+	userID := c.Locals("user_id")
+	sessionID := c.Locals("session_id")
+	if userID == nil || sessionID == nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User not authenticated",
+		})
+	}
+
+	revoked, err := services.NewSessionService().RevokeOtherSessions(userID.(string), sessionID.(string), models.SessionRevokedByUser)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to revoke sessions",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Other sessions revoked successfully",
+		"revoked": revoked,
+	})
+}
@@ -1,13 +1,22 @@
 package handlers
 
 import (
+	"errors"
+
 	"backend/config"
 	"backend/models"
+	"backend/services"
 
 	"github.com/gofiber/fiber/v2"
 	uuid "github.com/satori/go.uuid"
 )
 
+// CartResponse is the cart with products frequently bought with its contents
+type CartResponse struct {
+	models.Cart
+	Recommendations []models.Product `json:"recommendations"`
+}
+
 // GetCart returns the user's cart
 func GetCart(c *fiber.Ctx) error {
 	userID := c.Locals("user_id")
@@ -26,7 +35,7 @@ func GetCart(c *fiber.Ctx) error {
 	}
 
 	var cart models.Cart
-	if err := config.DB.Preload("CartItems.Product.Category").
+	if err := config.DB.Preload("CartItems.Product.Category").Preload("CartItems.Variant").
 		Where("user_id = ?", userUUID).First(&cart).Error; err != nil {
 		// Create new cart if none exists
 		cart = models.Cart{
@@ -39,7 +48,22 @@ func GetCart(c *fiber.Ctx) error {
 		}
 	}
 
-	return c.JSON(cart)
+	productIDs := make([]uuid.UUID, 0, len(cart.CartItems))
+	for _, item := range cart.CartItems {
+		productIDs = append(productIDs, item.ProductID)
+	}
+
+	recommendations, err := services.NewRecommendationService().ForCart(productIDs, relatedProductsLimit)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch recommendations",
+		})
+	}
+
+	return c.JSON(CartResponse{
+		Cart:            cart,
+		Recommendations: recommendations,
+	})
 }
 
 // AddCartItem adds an item to the cart
@@ -60,8 +84,9 @@ func AddCartItem(c *fiber.Ctx) error {
 	}
 
 	var req struct {
-		ProductID string `json:"product_id"`
-		Quantity  int    `json:"quantity"`
+		ProductID string  `json:"product_id"`
+		VariantID *string `json:"variant_id"`
+		Quantity  float64 `json:"quantity"`
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -89,37 +114,73 @@ func AddCartItem(c *fiber.Ctx) error {
 		}
 	}
 
-	// Check if product exists and has stock
-	var product models.Product
-	if err := config.DB.Where("id = ? AND is_active = ?", req.ProductID, true).First(&product).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Product not found",
+	// Check if product (or variant) exists and has stock
+	item, err := services.NewInventoryService().Resolve(req.ProductID, req.VariantID)
+	if err != nil {
+		return stockItemError(c, err)
+	}
+
+	// Quantities must fit the product's unit, e.g. whole boxes or 0.5 m of cable
+	if err := item.Product.ValidateQuantity(req.Quantity); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
 		})
 	}
+	req.Quantity = models.RoundQuantity(req.Quantity)
 
-	if product.StockQuantity < req.Quantity {
+	if item.Stock() < req.Quantity {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Insufficient stock",
 		})
 	}
 
 	// Check if item already exists in cart
+	existingQuery := config.DB.Where("cart_id = ? AND product_id = ?", cart.ID, item.Product.ID)
+	if item.Variant != nil {
+		existingQuery = existingQuery.Where("variant_id = ?", item.Variant.ID)
+	} else {
+		existingQuery = existingQuery.Where("variant_id IS NULL")
+	}
+
+	pricingService := services.NewPricingService()
+	customerGroup := pricingService.CustomerGroup(userID.(string))
+
 	var existingItem models.CartItem
-	if err := config.DB.Where("cart_id = ? AND product_id = ?", cart.ID, req.ProductID).First(&existingItem).Error; err == nil {
-		// Update quantity
-		newQuantity := existingItem.Quantity + req.Quantity
-		if err := config.DB.Model(&existingItem).Update("quantity", newQuantity).Error; err != nil {
+	if err := existingQuery.First(&existingItem).Error; err == nil {
+		// Update quantity, which may reach a lower price tier
+		newQuantity := models.RoundQuantity(existingItem.Quantity + req.Quantity)
+		unitPrice, err := pricingService.UnitPrice(item, newQuantity, customerGroup)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to price cart item",
+			})
+		}
+
+		if err := config.DB.Model(&existingItem).Updates(map[string]interface{}{
+			"quantity":   newQuantity,
+			"unit_price": unitPrice,
+		}).Error; err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 				"error": "Failed to update cart item",
 			})
 		}
 	} else {
+		unitPrice, err := pricingService.UnitPrice(item, req.Quantity, customerGroup)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to price cart item",
+			})
+		}
+
 		// Create new cart item
 		cartItem := models.CartItem{
 			CartID:    cart.ID,
-			ProductID: uuid.FromStringOrNil(req.ProductID),
+			ProductID: item.Product.ID,
 			Quantity:  req.Quantity,
-			UnitPrice: product.Price,
+			UnitPrice: unitPrice,
+		}
+		if item.Variant != nil {
+			cartItem.VariantID = &item.Variant.ID
 		}
 		if err := config.DB.Create(&cartItem).Error; err != nil {
 			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
@@ -150,7 +211,7 @@ func UpdateCartItem(c *fiber.Ctx) error {
 	}
 
 	var req struct {
-		Quantity int `json:"quantity"`
+		Quantity float64 `json:"quantity"`
 	}
 
 	if err := c.BodyParser(&req); err != nil {
@@ -175,21 +236,43 @@ func UpdateCartItem(c *fiber.Ctx) error {
 	}
 
 	// Check stock availability
-	var product models.Product
-	if err := config.DB.First(&product, cartItem.ProductID).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"error": "Product not found",
+	var variantID *string
+	if cartItem.VariantID != nil {
+		id := cartItem.VariantID.String()
+		variantID = &id
+	}
+
+	item, err := services.NewInventoryService().Resolve(cartItem.ProductID.String(), variantID)
+	if err != nil {
+		return stockItemError(c, err)
+	}
+
+	if err := item.Product.ValidateQuantity(req.Quantity); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
 		})
 	}
+	req.Quantity = models.RoundQuantity(req.Quantity)
 
-	if product.StockQuantity < req.Quantity {
+	if item.Stock() < req.Quantity {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": "Insufficient stock",
 		})
 	}
 
-	// Update quantity
-	if err := config.DB.Model(&cartItem).Update("quantity", req.Quantity).Error; err != nil {
+	pricingService := services.NewPricingService()
+	unitPrice, err := pricingService.UnitPrice(item, req.Quantity, pricingService.CustomerGroup(userID.(string)))
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to price cart item",
+		})
+	}
+
+	// Update quantity and re-price for the new quantity's tier
+	if err := config.DB.Model(&cartItem).Updates(map[string]interface{}{
+		"quantity":   req.Quantity,
+		"unit_price": unitPrice,
+	}).Error; err != nil {
 		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
 			"error": "Failed to update cart item",
 		})
@@ -373,3 +456,25 @@ func RemoveWishlistItem(c *fiber.Ctx) error {
 		"message": "Wishlist item removed successfully",
 	})
 }
+
+// stockItemError maps inventory lookup errors to HTTP responses
+func stockItemError(c *fiber.Ctx, err error) error {
+	switch {
+	case errors.Is(err, services.ErrProductNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Product not found",
+		})
+	case errors.Is(err, services.ErrVariantNotFound):
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Variant not found",
+		})
+	case errors.Is(err, services.ErrVariantRequired):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	default:
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check stock",
+		})
+	}
+}
@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"backend/config"
+	"backend/models"
+	"backend/services"
+	"backend/utils"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// VerifyTwoFactorLogin completes a login that is waiting for its second factor
+func VerifyTwoFactorLogin(c *fiber.Ctx) error {
+	var req struct {
+		ChallengeToken string `json:"challenge_token"`
+		Code           string `json:"code"`
+		RecoveryCode   string `json:"recovery_code"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if req.ChallengeToken == "" || (req.Code == "" && req.RecoveryCode == "") {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Challenge token and code or recovery_code are required",
+		})
+	}
+
+	claims, err := utils.ValidateChallengeToken(req.ChallengeToken, utils.ChallengePurposeTwoFactor)
+	if err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid or expired challenge token",
+		})
+	}
+
+	var user models.User
+	if err := config.DB.Where("id = ?", claims.UserID).First(&user).Error; err != nil {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid credentials",
+		})
+	}
+
+	if !user.IsActive {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Account is deactivated",
+		})
+	}
+
+	if !user.TwoFactorEnabled {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid two-factor code",
+		})
+	}
+
+	twoFactorService := services.NewTwoFactorService()
+	verified := false
+	if req.Code != "" {
+		verified = twoFactorService.VerifyCode(&user, req.Code)
+	} else {
+		verified = twoFactorService.VerifyRecoveryCode(&user, req.RecoveryCode)
+	}
+
+	if !verified {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid two-factor code",
+		})
+	}
+
+	tokens, err := services.NewSessionService().CreateSession(&user, c.Get("User-Agent"), c.IP(), true)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to generate token",
+		})
+	}
+
+	// Clear password hash from response
+	user.PasswordHash = ""
+
+	return c.JSON(AuthResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         user,
+	})
+}
+
+// GetTwoFactorStatus reports whether two-factor authentication is enabled for the current user
+func GetTwoFactorStatus(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if user == nil {
+		return err
+	}
+
+	twoFactorService := services.NewTwoFactorService()
+	return c.JSON(fiber.Map{
+		"enabled":                  user.TwoFactorEnabled,
+		"required":                 twoFactorService.IsRequired(string(user.Role)),
+		"remaining_recovery_codes": twoFactorService.RemainingRecoveryCodes(user),
+	})
+}
+
+// SetupTwoFactor starts enrollment and returns the secret and provisioning URI for a QR code
+func SetupTwoFactor(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if user == nil {
+		return err
+	}
+
+	if user.TwoFactorEnabled {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Two-factor authentication is already enabled",
+		})
+	}
+
+	enrollment, err := services.NewTwoFactorService().BeginEnrollment(user)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to start two-factor enrollment",
+		})
+	}
+
+	return c.JSON(enrollment)
+}
+
+// EnableTwoFactor confirms enrollment with a code and returns recovery codes
+func EnableTwoFactor(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if user == nil {
+		return err
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if user.TwoFactorEnabled {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "Two-factor authentication is already enabled",
+		})
+	}
+
+	codes, err := services.NewTwoFactorService().Enable(user, req.Code)
+	if err != nil {
+		if errors.Is(err, services.ErrTwoFactorNotEnrolled) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Start enrollment before enabling two-factor authentication",
+			})
+		}
+		if errors.Is(err, services.ErrTwoFactorInvalidCode) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid two-factor code",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to enable two-factor authentication",
+		})
+	}
+
+	// The current session has just proven the second factor
+	if sessionID, ok := c.Locals("session_id").(string); ok {
+		if err := services.NewSessionService().MarkTwoFactorVerified(sessionID); err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+				"error": "Failed to update session",
+			})
+		}
+	}
+
+	return c.JSON(fiber.Map{
+		"message":        "Two-factor authentication enabled successfully",
+		"recovery_codes": codes,
+	})
+}
+
+// DisableTwoFactor turns off two-factor authentication after re-checking the password and a code
+func DisableTwoFactor(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if user == nil {
+		return err
+	}
+
+	var req struct {
+		Password string `json:"password"`
+		Code     string `json:"code"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	twoFactorService := services.NewTwoFactorService()
+	if twoFactorService.IsRequired(string(user.Role)) {
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+			"error": "Two-factor authentication is required for your role",
+		})
+	}
+
+	if !user.TwoFactorEnabled {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Two-factor authentication is not enabled",
+		})
+	}
+
+	if !utils.VerifyPassword(req.Password, user.PasswordHash) || !twoFactorService.VerifyCode(user, req.Code) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid password or two-factor code",
+		})
+	}
+
+	if err := twoFactorService.Disable(user); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to disable two-factor authentication",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Two-factor authentication disabled successfully",
+	})
+}
+
+// RegenerateRecoveryCodes replaces the current user's recovery codes
+func RegenerateRecoveryCodes(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if user == nil {
+		return err
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	twoFactorService := services.NewTwoFactorService()
+	if !user.TwoFactorEnabled {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Two-factor authentication is not enabled",
+		})
+	}
+
+	if !twoFactorService.VerifyCode(user, req.Code) {
+		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "Invalid two-factor code",
+		})
+	}
+
+	codes, err := twoFactorService.RegenerateRecoveryCodes(user)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to regenerate recovery codes",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":        "Recovery codes regenerated successfully",
+		"recovery_codes": codes,
+	})
+}
+
+// currentUser loads the authenticated user. When it returns nil the error
+// response has already been written and err is the result of writing it.
+func currentUser(c *fiber.Ctx) (*models.User, error) {
+	userID := c.Locals("user_id")
+	if userID == nil {
+		return nil, c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+			"error": "User not authenticated",
+		})
+	}
+
+	var user models.User
+	if err := config.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		return nil, c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "User not found",
+		})
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,282 @@
+package handlers
+
+import (
+	"backend/config"
+	"backend/models"
+	"backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+type VariantRequest struct {
+	SKU           *string               `json:"sku"`
+	Name          *string               `json:"name"`
+	Options       models.VariantOptions `json:"options"`
+	Price         *float64              `json:"price"`
+	StockQuantity *float64              `json:"stock_quantity"`
+	Position      *int                  `json:"position"`
+	IsActive      *bool                 `json:"is_active"`
+}
+
+// AdminGetProductVariants lists all variants of a product, including inactive ones
+func AdminGetProductVariants(c *fiber.Ctx) error {
+	var variants []models.ProductVariant
+	if err := config.DB.Where("product_id = ?", c.Params("id")).
+		Order("position ASC, name ASC").
+		Find(&variants).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch variants",
+		})
+	}
+
+	return c.JSON(variants)
+}
+
+// AdminCreateProductVariant adds a variant to a product
+func AdminCreateProductVariant(c *fiber.Ctx) error {
+	var product models.Product
+	if err := config.DB.First(&product, "id = ?", c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Product not found",
+		})
+	}
+
+	if product.IsBundle {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Bundles cannot have variants",
+		})
+	}
+
+	// Bundles holding the product itself would lose track of its stock once
+	// it moves to variants
+	var bundleUses int64
+	if err := config.DB.Model(&models.BundleComponent{}).
+		Where("component_product_id = ? AND component_variant_id IS NULL", product.ID).
+		Count(&bundleUses).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check product usage",
+		})
+	}
+
+	if bundleUses > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Cannot add variants to a product that is part of a bundle",
+		})
+	}
+
+	var req VariantRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if req.SKU == nil || *req.SKU == "" || req.Name == nil || *req.Name == "" || req.Price == nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "SKU, name and price are required",
+		})
+	}
+
+	if *req.Price < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Price must be non-negative",
+		})
+	}
+
+	if req.StockQuantity != nil && *req.StockQuantity < 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Stock quantity must be non-negative",
+		})
+	}
+
+	if skuTaken(*req.SKU, "") {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+			"error": "A product or variant with this SKU already exists",
+		})
+	}
+
+	variant := models.ProductVariant{
+		ProductID: product.ID,
+		SKU:       *req.SKU,
+		Name:      *req.Name,
+		Options:   req.Options,
+		Price:     *req.Price,
+		IsActive:  true,
+	}
+	if req.StockQuantity != nil {
+		variant.StockQuantity = models.RoundQuantity(*req.StockQuantity)
+	}
+	if req.Position != nil {
+		variant.Position = *req.Position
+	}
+	if req.IsActive != nil {
+		variant.IsActive = *req.IsActive
+	}
+
+	if err := config.DB.Create(&variant).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to create variant",
+		})
+	}
+
+	recordPriceChange(c, models.PriceChange{ProductID: product.ID, VariantID: &variant.ID, Price: variant.Price})
+
+	return c.Status(fiber.StatusCreated).JSON(variant)
+}
+
+// AdminUpdateProductVariant updates a variant of a product
+func AdminUpdateProductVariant(c *fiber.Ctx) error {
+	var variant models.ProductVariant
+	if err := config.DB.First(&variant, "id = ? AND product_id = ?", c.Params("variantId"), c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Variant not found",
+		})
+	}
+
+	var req VariantRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	updates := map[string]interface{}{}
+
+	if req.SKU != nil && *req.SKU != variant.SKU {
+		if skuTaken(*req.SKU, variant.ID.String()) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "A product or variant with this SKU already exists",
+			})
+		}
+		updates["sku"] = *req.SKU
+	}
+
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+
+	if req.Options != nil {
+		updates["options"] = req.Options
+	}
+
+	if req.Price != nil {
+		if *req.Price < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Price must be non-negative",
+			})
+		}
+		updates["price"] = *req.Price
+	}
+
+	if req.StockQuantity != nil {
+		if *req.StockQuantity < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Stock quantity must be non-negative",
+			})
+		}
+		updates["stock_quantity"] = models.RoundQuantity(*req.StockQuantity)
+	}
+
+	if req.Position != nil {
+		updates["position"] = *req.Position
+	}
+
+	if req.IsActive != nil {
+		updates["is_active"] = *req.IsActive
+	}
+
+	priceChanged := req.Price != nil && *req.Price != variant.Price
+
+	if err := config.DB.Model(&variant).Updates(updates).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update variant",
+		})
+	}
+
+	if priceChanged {
+		recordPriceChange(c, models.PriceChange{
+			ProductID:   variant.ProductID,
+			VariantID:   &variant.ID,
+			Price:       *req.Price,
+			SalePricing: variant.SalePricing,
+		})
+	}
+
+	return c.JSON(variant)
+}
+
+// AdminDeleteProductVariant deletes a variant that has never been ordered.
+// Ordered variants should be deactivated instead so order history stays intact.
+func AdminDeleteProductVariant(c *fiber.Ctx) error {
+	var variant models.ProductVariant
+	if err := config.DB.First(&variant, "id = ? AND product_id = ?", c.Params("variantId"), c.Params("id")).Error; err != nil {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+			"error": "Variant not found",
+		})
+	}
+
+	var orderCount int64
+	if err := config.DB.Model(&models.OrderItem{}).Where("variant_id = ?", variant.ID).Count(&orderCount).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check variant usage",
+		})
+	}
+
+	if orderCount > 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Cannot delete a variant that has been ordered; deactivate it instead",
+		})
+	}
+
+	variantID := variant.ID.String()
+	inBundle, err := services.NewBundleService().IsComponent(variant.ProductID.String(), &variantID)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to check variant usage",
+		})
+	}
+
+	if inBundle {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Cannot delete a variant that is part of a bundle",
+		})
+	}
+
+	if err := config.DB.Where("variant_id = ?", variant.ID).Delete(&models.CartItem{}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to remove variant from carts",
+		})
+	}
+
+	if err := config.DB.Where("variant_id = ?", variant.ID).Delete(&models.PriceTier{}).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete variant price tiers",
+		})
+	}
+
+	if err := config.DB.Delete(&variant).Error; err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to delete variant",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Variant deleted successfully",
+	})
+}
+
+// skuTaken reports whether a SKU is used by a product or by a variant other than excludeVariantID
+func skuTaken(sku, excludeVariantID string) bool {
+	var productCount int64
+	config.DB.Model(&models.Product{}).Where("sku = ?", sku).Count(&productCount)
+
+	variantQuery := config.DB.Model(&models.ProductVariant{}).Where("sku = ?", sku)
+	if excludeVariantID != "" {
+		variantQuery = variantQuery.Where("id != ?", excludeVariantID)
+	}
+
+	var variantCount int64
+	variantQuery.Count(&variantCount)
+
+	return productCount > 0 || variantCount > 0
+}
@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"backend/services"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// VerifyEmail confirms an email address using the token from the verification link
+func VerifyEmail(c *fiber.Ctx) error {
+	var req struct {
+		Token string `json:"token"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if req.Token == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Token is required",
+		})
+	}
+
+	user, err := services.NewVerificationService().VerifyEmail(req.Token)
+	if err != nil {
+		if errors.Is(err, services.ErrVerificationInvalid) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid or expired verification token",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to verify email",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":           "Email verified successfully",
+		"email_verified_at": user.EmailVerifiedAt,
+	})
+}
+
+// ResendEmailVerification sends a new verification link to the current user
+func ResendEmailVerification(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if user == nil {
+		return err
+	}
+
+	if err := services.NewVerificationService().SendEmailVerification(user); err != nil {
+		if errors.Is(err, services.ErrAlreadyVerified) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Email is already verified",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to send verification email",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Verification email sent",
+	})
+}
+
+// SendPhoneVerification texts a verification code to the current user's phone
+func SendPhoneVerification(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if user == nil {
+		return err
+	}
+
+	if err := services.NewVerificationService().SendPhoneVerification(user); err != nil {
+		if errors.Is(err, services.ErrNoPhoneNumber) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Add a phone number to your profile first",
+			})
+		}
+		if errors.Is(err, services.ErrAlreadyVerified) {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{
+				"error": "Phone number is already verified",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to send verification code",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message": "Verification code sent",
+	})
+}
+
+// VerifyPhone confirms the current user's phone number with an SMS code
+func VerifyPhone(c *fiber.Ctx) error {
+	user, err := currentUser(c)
+	if user == nil {
+		return err
+	}
+
+	var req struct {
+		Code string `json:"code"`
+	}
+
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": err.Error(),
+		})
+	}
+
+	if req.Code == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Code is required",
+		})
+	}
+
+	if err := services.NewVerificationService().VerifyPhone(user, req.Code); err != nil {
+		switch {
+		case errors.Is(err, services.ErrNoPhoneNumber):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Add a phone number to your profile first",
+			})
+		case errors.Is(err, services.ErrVerificationTooManyAttempts):
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error": "Too many attempts. Request a new code",
+			})
+		case errors.Is(err, services.ErrVerificationInvalid):
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": "Invalid or expired verification code",
+			})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to verify phone number",
+		})
+	}
+
+	return c.JSON(fiber.Map{
+		"message":           "Phone number verified successfully",
+		"phone_verified_at": user.PhoneVerifiedAt,
+	})
+}
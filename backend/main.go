@@ -20,7 +20,7 @@ func initializeServices() {
 	log.Println("Initializing external services...")
 
 	// Initialize Redis service
-	redisService := services.NewRedisService()
+	redisService := services.SharedRedisService()
 	if err := redisService.HealthCheck(); err != nil {
 		log.Printf("Warning: Redis connection failed: %v", err)
 	} else {
@@ -63,6 +63,9 @@ func main() {
 	// Run seeder
 	seeders.SeedDatabase()
 
+	// Recompute "frequently bought together" products in the background
+	go services.NewRecommendationService().RunPeriodically()
+
 	// Create Fiber app with production configuration
 	app := fiber.New(fiber.Config{
 		ErrorHandler: middleware.ErrorHandler(),
@@ -81,7 +84,8 @@ func main() {
 	app.Use(cors.New(cors.Config{
 		AllowOrigins:     os.Getenv("CORS_ALLOWED_ORIGINS"),
 		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders:     "Origin,Content-Type,Accept,Authorization,X-Request-ID",
+		AllowHeaders:     "Origin,Content-Type,Accept,Authorization,X-Request-ID,If-None-Match",
+		ExposeHeaders:    "ETag,X-Cache",
 		AllowCredentials: true,
 		MaxAge:           300,
 	}))
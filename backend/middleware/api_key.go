@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"strings"
+
+	"backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// APIKeyOrAuthMiddleware accepts either an API key, sent as X-API-Key or as a
+// bearer token, or a user access token handled by AuthMiddleware
+func APIKeyOrAuthMiddleware() fiber.Handler {
+	authMiddleware := AuthMiddleware()
+
+	return func(c *fiber.Ctx) error {
+		rawKey := c.Get("X-API-Key")
+		if rawKey == "" {
+			bearer := strings.TrimPrefix(c.Get("Authorization"), "Bearer ")
+			if services.IsAPIKey(bearer) {
+				rawKey = bearer
+			}
+		}
+
+		if rawKey == "" {
+			return authMiddleware(c)
+		}
+
+		apiKeyService := services.NewAPIKeyService()
+		key, err := apiKeyService.Authenticate(rawKey)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Invalid, expired or revoked API key",
+			})
+		}
+
+		if allowed, retryAfter := apiKeyService.Allow(key); !allowed {
+			return c.Status(fiber.StatusTooManyRequests).JSON(fiber.Map{
+				"error":       "Rate limit exceeded",
+				"message":     "Too many requests for this API key. Please try again later.",
+				"retry_after": int(retryAfter.Seconds()) + 1,
+			})
+		}
+
+		apiKeyService.Touch(key, c.IP())
+
+		c.Locals("api_key", key)
+
+		return c.Next()
+	}
+}
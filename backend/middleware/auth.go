@@ -3,6 +3,8 @@ package middleware
 import (
 	"strings"
 
+	"backend/models"
+	"backend/services"
 	"backend/utils"
 
 	"github.com/gofiber/fiber/v2"
@@ -32,28 +34,63 @@ func AuthMiddleware() fiber.Handler {
 			})
 		}
 
+		// Reject tokens whose session was revoked by logout, password reset or an admin
+		sessionService := services.NewSessionService()
+		session, err := sessionService.GetActiveSession(claims.SessionID)
+		if err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "Session has been revoked",
+			})
+		}
+		sessionService.TouchSession(session, c.IP())
+
 		// Set user info in context
 		c.Locals("user_id", claims.UserID)
 		c.Locals("user_email", claims.Email)
 		c.Locals("user_role", claims.Role)
+		c.Locals("session_id", claims.SessionID)
+		c.Locals("two_factor_verified", session.TwoFactorVerified)
 
 		return c.Next()
 	}
 }
 
-// AdminMiddleware protects admin-only routes
-func AdminMiddleware() fiber.Handler {
+// RequirePermission protects routes that need at least one of the given
+// permissions through the user's role or the API key's scopes
+func RequirePermission(permissions ...models.Permission) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		role := c.Locals("user_role")
-		if role == nil {
+		// API keys are limited to the scopes they were issued with
+		if key, ok := c.Locals("api_key").(*models.APIKey); ok {
+			for _, permission := range permissions {
+				if key.HasScope(permission) {
+					return c.Next()
+				}
+			}
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":                "API key is missing the required scope",
+				"required_permissions": permissions,
+			})
+		}
+
+		role, ok := c.Locals("user_role").(string)
+		if !ok || role == "" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "User not authenticated",
 			})
 		}
 
-		if role != "admin" {
+		if !services.NewRoleService().HasAnyPermission(role, permissions...) {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":                "Insufficient permissions",
+				"required_permissions": permissions,
+			})
+		}
+
+		// Admins may be required to have signed in with a second factor
+		if services.NewTwoFactorService().IsRequired(role) && c.Locals("two_factor_verified") != true {
 			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
-				"error": "Admin access required",
+				"error":               "Two-factor authentication is required for admin access",
+				"two_factor_required": true,
 			})
 		}
 
@@ -79,10 +116,15 @@ func OptionalAuthMiddleware() fiber.Handler {
 			return c.Next()
 		}
 
+		if _, err := services.NewSessionService().GetActiveSession(claims.SessionID); err != nil {
+			return c.Next()
+		}
+
 		// Set user info in context if token is valid
 		c.Locals("user_id", claims.UserID)
 		c.Locals("user_email", claims.Email)
 		c.Locals("user_role", claims.Role)
+		c.Locals("session_id", claims.SessionID)
 
 		return c.Next()
 	}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// CatalogCache serves public catalog responses from Redis, caching them on
+// a miss. Only the query parameters the handler reads, listed in params, key
+// the cache, so unknown parameters cannot create entries. Responses carry an
+// ETag, and requests whose If-None-Match matches it get 304 Not Modified
+// without a body.
+func CatalogCache(params ...string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if c.Method() != fiber.MethodGet {
+			return c.Next()
+		}
+
+		query := make(map[string]string, len(params))
+		for _, param := range params {
+			query[param] = c.Query(param)
+		}
+
+		// The key is taken before the handler reads the database, so a change
+		// made meanwhile bumps the generation past the entry stored below
+		cache := services.NewCatalogCacheService()
+		key := cache.Key(c.Path(), query)
+
+		if cached, ok := cache.Get(key); ok {
+			c.Set("X-Cache", "HIT")
+			c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+			return sendFresh(c, cached.ETag, cached.Body)
+		}
+
+		if err := c.Next(); err != nil {
+			return err
+		}
+		if c.Response().StatusCode() != fiber.StatusOK {
+			return nil
+		}
+
+		cached := cache.Set(key, c.Response().Body())
+		c.Set("X-Cache", "MISS")
+		return sendFresh(c, cached.ETag, cached.Body)
+	}
+}
+
+// InvalidateCatalogCache retires cached catalog responses after a request
+// that changes the catalog succeeds
+func InvalidateCatalogCache() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if err := c.Next(); err != nil {
+			return err
+		}
+
+		if status := c.Response().StatusCode(); status >= 200 && status < 300 {
+			services.NewCatalogCacheService().Invalidate()
+		}
+		return nil
+	}
+}
+
+// sendFresh sends a body with its ETag, or 304 Not Modified when the client
+// already has it. Clients must revalidate before reusing a response.
+func sendFresh(c *fiber.Ctx, etag string, body []byte) error {
+	c.Set(fiber.HeaderETag, etag)
+	c.Set(fiber.HeaderCacheControl, "public, no-cache")
+
+	if c.Get(fiber.HeaderIfNoneMatch) != "" && c.Fresh() {
+		c.Response().ResetBody()
+		c.Status(fiber.StatusNotModified)
+		return nil
+	}
+
+	return c.Status(fiber.StatusOK).Send(body)
+}
@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"backend/config"
+	"backend/models"
+	"backend/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// VerifiedEmailMiddleware blocks the route until the user has verified their
+// email address, when REQUIRE_EMAIL_VERIFICATION is enabled
+func VerifiedEmailMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !services.EmailVerificationRequired() {
+			return c.Next()
+		}
+
+		userID := c.Locals("user_id")
+		if userID == nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "User not authenticated",
+			})
+		}
+
+		var user models.User
+		if err := config.DB.Select("id", "email_verified_at").Where("id = ?", userID).First(&user).Error; err != nil {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "User not found",
+			})
+		}
+
+		if !user.IsEmailVerified() {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error":                       "Please verify your email address before checking out",
+				"email_verification_required": true,
+			})
+		}
+
+		return c.Next()
+	}
+}
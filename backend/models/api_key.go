@@ -0,0 +1,44 @@
+package models
+
+import (
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// APIKey authenticates a machine client, such as a warehouse scanner, with a
+// fixed set of permission scopes. Only the SHA-256 hash of the key is stored.
+type APIKey struct {
+	Base
+	Name        string         `gorm:"not null" json:"name"`
+	Prefix      string         `gorm:"index;not null" json:"prefix"`
+	KeyHash     string         `gorm:"uniqueIndex;not null" json:"-"`
+	Scopes      PermissionList `gorm:"type:jsonb" json:"scopes"`
+	RateLimit   int            `gorm:"not null" json:"rate_limit"` // requests per minute
+	ExpiresAt   *time.Time     `json:"expires_at,omitempty"`
+	LastUsedAt  *time.Time     `json:"last_used_at,omitempty"`
+	LastUsedIP  string         `json:"last_used_ip,omitempty"`
+	RevokedAt   *time.Time     `json:"revoked_at,omitempty"`
+	CreatedByID uuid.UUID      `gorm:"not null;index" json:"created_by_id"`
+
+	// Relationships
+	CreatedBy User `gorm:"foreignKey:CreatedByID" json:"-"`
+}
+
+// IsActive reports whether the key can still be used
+func (k *APIKey) IsActive() bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || time.Now().Before(*k.ExpiresAt)
+}
+
+// HasScope reports whether the key was granted the permission
+func (k *APIKey) HasScope(permission Permission) bool {
+	for _, scope := range k.Scopes {
+		if scope == permission {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+type AttributeType string
+
+const (
+	AttributeTypeText    AttributeType = "text"
+	AttributeTypeNumber  AttributeType = "number"
+	AttributeTypeBoolean AttributeType = "boolean"
+	AttributeTypeEnum    AttributeType = "enum"
+)
+
+// AttributeDefinition is an admin-defined specification, such as voltage or
+// material, for products in a category and all of its subcategories
+type AttributeDefinition struct {
+	Base
+	CategoryID   uuid.UUID        `gorm:"not null;uniqueIndex:idx_attribute_category_slug" json:"category_id"`
+	Name         string           `gorm:"not null" json:"name"`
+	Slug         string           `gorm:"not null;uniqueIndex:idx_attribute_category_slug" json:"slug"`
+	Type         AttributeType    `gorm:"not null" json:"type"`
+	Unit         string           `json:"unit,omitempty"`
+	Options      AttributeOptions `gorm:"type:jsonb" json:"options,omitempty"` // allowed values for enum attributes
+	IsFilterable bool             `gorm:"default:true" json:"is_filterable"`
+	Position     int              `gorm:"not null;default:0" json:"position"`
+
+	// Relationships
+	Category *Category `gorm:"foreignKey:CategoryID" json:"category,omitempty"`
+}
+
+// ProductAttributeValue stores a product's value for one attribute in the
+// column matching the attribute's type
+type ProductAttributeValue struct {
+	Base
+	ProductID   uuid.UUID `gorm:"not null;uniqueIndex:idx_product_attribute" json:"product_id"`
+	AttributeID uuid.UUID `gorm:"not null;uniqueIndex:idx_product_attribute;index" json:"attribute_id"`
+	TextValue   *string   `gorm:"index" json:"text_value,omitempty"`
+	NumberValue *float64  `gorm:"index" json:"number_value,omitempty"`
+	BoolValue   *bool     `json:"bool_value,omitempty"`
+
+	// Relationships
+	Product   *Product             `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+	Attribute *AttributeDefinition `gorm:"foreignKey:AttributeID" json:"attribute,omitempty"`
+}
+
+// IsValidAttributeType reports whether the attribute type is known
+func IsValidAttributeType(t AttributeType) bool {
+	switch t {
+	case AttributeTypeText, AttributeTypeNumber, AttributeTypeBoolean, AttributeTypeEnum:
+		return true
+	}
+	return false
+}
+
+// AttributeOptions is a custom type for handling the JSON array of enum values
+type AttributeOptions []string
+
+func (ao AttributeOptions) Value() (driver.Value, error) {
+	if ao == nil {
+		return json.Marshal([]string{})
+	}
+	return json.Marshal(ao)
+}
+
+func (ao *AttributeOptions) Scan(value interface{}) error {
+	if value == nil {
+		*ao = nil
+		return nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, ao)
+	case string:
+		return json.Unmarshal([]byte(v), ao)
+	default:
+		return errors.New("cannot scan AttributeOptions")
+	}
+}
+
+// Allows reports whether value is one of the enum options
+func (ao AttributeOptions) Allows(value string) bool {
+	for _, option := range ao {
+		if option == value {
+			return true
+		}
+	}
+	return false
+}
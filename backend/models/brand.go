@@ -0,0 +1,12 @@
+package models
+
+// Brand is the manufacturer of a product, e.g. Dangote or Berger Paints.
+// Products may have no brand, such as loose sand or unbranded fittings.
+type Brand struct {
+	Base
+	Name        string `gorm:"not null" json:"name"`
+	Slug        string `gorm:"uniqueIndex;not null" json:"slug"`
+	Description string `gorm:"type:text" json:"description"`
+	LogoURL     string `json:"logo_url"`
+	IsActive    bool   `gorm:"default:true" json:"is_active"`
+}
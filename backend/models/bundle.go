@@ -0,0 +1,58 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// BundleComponent is one product, or variant, a bundle such as a plumbing
+// starter kit is made of, with the quantity in each bundle. Bundles hold no
+// stock of their own: their stock quantity is derived from the components'
+// by a database trigger, and selling a bundle deducts the components.
+type BundleComponent struct {
+	Base
+	BundleID           uuid.UUID  `gorm:"not null;index" json:"bundle_id"`
+	ComponentProductID uuid.UUID  `gorm:"not null;index" json:"component_product_id"`
+	ComponentVariantID *uuid.UUID `gorm:"index" json:"component_variant_id,omitempty"`
+	Quantity           float64    `gorm:"type:decimal(12,3);not null" json:"quantity"`
+
+	// Relationships
+	Component *Product        `gorm:"foreignKey:ComponentProductID" json:"component,omitempty"`
+	Variant   *ProductVariant `gorm:"foreignKey:ComponentVariantID" json:"variant,omitempty"`
+}
+
+// BundleDeduction records the component stock taken for one bundle order
+// line, so cancelling restores exactly that even if the bundle has changed
+type BundleDeduction struct {
+	ProductID uuid.UUID  `json:"product_id"`
+	VariantID *uuid.UUID `json:"variant_id,omitempty"`
+	Quantity  float64    `json:"quantity"`
+}
+
+type BundleDeductions []BundleDeduction
+
+func (bd BundleDeductions) Value() (driver.Value, error) {
+	if bd == nil {
+		return nil, nil
+	}
+	return json.Marshal(bd)
+}
+
+func (bd *BundleDeductions) Scan(value interface{}) error {
+	if value == nil {
+		*bd = nil
+		return nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, bd)
+	case string:
+		return json.Unmarshal([]byte(v), bd)
+	default:
+		return errors.New("cannot scan BundleDeductions")
+	}
+}
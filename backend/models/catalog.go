@@ -4,16 +4,23 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
+	"time"
+
 	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
 )
 
 type Category struct {
 	Base
-	Name string `gorm:"not null" json:"name"`
-	Slug string `gorm:"uniqueIndex;not null" json:"slug"`
+	Name     string     `gorm:"not null" json:"name"`
+	Slug     string     `gorm:"uniqueIndex;not null" json:"slug"`
+	ParentID *uuid.UUID `gorm:"index" json:"parent_id"`
+	Position int        `gorm:"not null;default:0" json:"position"`
 	
 	// Relationships
-	Products []Product `gorm:"foreignKey:CategoryID" json:"products,omitempty"`
+	Parent   *Category  `gorm:"foreignKey:ParentID" json:"parent,omitempty"`
+	Children []Category `gorm:"foreignKey:ParentID" json:"children,omitempty"`
+	Products []Product  `gorm:"foreignKey:CategoryID" json:"products,omitempty"`
 }
 
 type Product struct {
@@ -22,19 +29,94 @@ type Product struct {
 	Name           string      `gorm:"not null" json:"name"`
 	Slug           string      `gorm:"uniqueIndex;not null" json:"slug"`
 	CategoryID     uuid.UUID   `gorm:"not null" json:"category_id"`
+	BrandID        *uuid.UUID  `gorm:"index" json:"brand_id"`
 	Description    string      `gorm:"type:text" json:"description"`
 	Price          float64     `gorm:"type:decimal(10,2);not null" json:"price"`
-	StockQuantity  int         `gorm:"not null;default:0" json:"stock_quantity"`
+	StockQuantity  float64     `gorm:"type:decimal(12,3);not null;default:0" json:"stock_quantity"`
 	ImagesJSON     ImagesArray `gorm:"type:jsonb" json:"images_json"`
 	IsActive       bool        `gorm:"default:true" json:"is_active"`
+	IsBundle       bool        `gorm:"not null;default:false;index" json:"is_bundle"`
+	Measure
+	SalePricing
+	DisplayPrices
+	ProductRating
 	
 	// Relationships
 	Category     Category     `gorm:"foreignKey:CategoryID" json:"category,omitempty"`
+	Brand        *Brand       `gorm:"foreignKey:BrandID" json:"brand,omitempty"`
+	Variants     []ProductVariant `gorm:"foreignKey:ProductID" json:"variants,omitempty"`
+	BundleItems  []BundleComponent `gorm:"foreignKey:BundleID" json:"bundle_items,omitempty"`
 	CartItems   []CartItem   `gorm:"foreignKey:ProductID" json:"cart_items,omitempty"`
 	OrderItems  []OrderItem  `gorm:"foreignKey:ProductID" json:"order_items,omitempty"`
 	Wishlists   []Wishlist   `gorm:"foreignKey:ProductID" json:"wishlists,omitempty"`
 }
 
+// ProductVariant is a purchasable version of a product, such as a nail length
+// or a mabati gauge, with its own SKU, price and stock. Products without
+// variants are sold using the product's own SKU, price and stock.
+type ProductVariant struct {
+	Base
+	ProductID     uuid.UUID      `gorm:"not null;index" json:"product_id"`
+	SKU           string         `gorm:"uniqueIndex;not null" json:"sku"`
+	Name          string         `gorm:"not null" json:"name"`
+	Options       VariantOptions `gorm:"type:jsonb" json:"options"`
+	Price         float64        `gorm:"type:decimal(10,2);not null" json:"price"`
+	StockQuantity float64        `gorm:"type:decimal(12,3);not null;default:0" json:"stock_quantity"`
+	Position      int            `gorm:"not null;default:0" json:"position"`
+	IsActive      bool           `gorm:"default:true" json:"is_active"`
+	SalePricing
+	DisplayPrices
+
+	// Relationships
+	Product *Product `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+}
+
+// PriceNow returns the product's price now, taking any running sale into account
+func (p *Product) PriceNow() float64 {
+	return p.PriceAt(p.Price, time.Now())
+}
+
+func (p *Product) AfterFind(tx *gorm.DB) error {
+	p.DisplayPrices.Set(p.Price, p.SalePricing)
+	return nil
+}
+
+// PriceNow returns the variant's price now, taking any running sale into account
+func (v *ProductVariant) PriceNow() float64 {
+	return v.PriceAt(v.Price, time.Now())
+}
+
+func (v *ProductVariant) AfterFind(tx *gorm.DB) error {
+	v.DisplayPrices.Set(v.Price, v.SalePricing)
+	return nil
+}
+
+// VariantOptions maps option names to values, e.g. {"length": "3in", "gauge": "30"}
+type VariantOptions map[string]string
+
+func (vo VariantOptions) Value() (driver.Value, error) {
+	if vo == nil {
+		return json.Marshal(map[string]string{})
+	}
+	return json.Marshal(vo)
+}
+
+func (vo *VariantOptions) Scan(value interface{}) error {
+	if value == nil {
+		*vo = nil
+		return nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, vo)
+	case string:
+		return json.Unmarshal([]byte(v), vo)
+	default:
+		return errors.New("cannot scan VariantOptions")
+	}
+}
+
 // ImagesArray is a custom type for handling JSON array of image URLs
 type ImagesArray []string
 
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+type ImportJobStatus string
+
+const (
+	ImportJobPending   ImportJobStatus = "pending"
+	ImportJobRunning   ImportJobStatus = "running"
+	ImportJobCompleted ImportJobStatus = "completed"
+	ImportJobFailed    ImportJobStatus = "failed"
+)
+
+// ProductImportJob tracks a CSV product import running in the background. A
+// dry run validates every row and counts what would change without saving.
+type ProductImportJob struct {
+	Base
+	FileName      string          `json:"file_name"`
+	DryRun        bool            `gorm:"default:false" json:"dry_run"`
+	Status        ImportJobStatus `gorm:"not null;default:'pending';index" json:"status"`
+	TotalRows     int             `gorm:"not null;default:0" json:"total_rows"`
+	ProcessedRows int             `gorm:"not null;default:0" json:"processed_rows"`
+	CreatedCount  int             `gorm:"not null;default:0" json:"created_count"`
+	UpdatedCount  int             `gorm:"not null;default:0" json:"updated_count"`
+	FailedCount   int             `gorm:"not null;default:0" json:"failed_count"`
+	RowErrors     ImportRowErrors `gorm:"type:jsonb" json:"row_errors"`
+	Error         string          `json:"error,omitempty"`
+	CreatedByID   *uuid.UUID      `json:"created_by_id,omitempty"`
+	StartedAt     *time.Time      `json:"started_at,omitempty"`
+	FinishedAt    *time.Time      `json:"finished_at,omitempty"`
+}
+
+// ImportRowError reports why a CSV row was rejected. Row counts from 1 for
+// the header, so it matches the line number shown in a spreadsheet.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	SKU     string `json:"sku,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+type ImportRowErrors []ImportRowError
+
+func (ie ImportRowErrors) Value() (driver.Value, error) {
+	if ie == nil {
+		return json.Marshal([]ImportRowError{})
+	}
+	return json.Marshal(ie)
+}
+
+func (ie *ImportRowErrors) Scan(value interface{}) error {
+	if value == nil {
+		*ie = nil
+		return nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, ie)
+	case string:
+		return json.Unmarshal([]byte(v), ie)
+	default:
+		return errors.New("cannot scan ImportRowErrors")
+	}
+}
@@ -0,0 +1,84 @@
+package models
+
+import (
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+// PriceTier is a quantity break: buying at least MinQuantity of a product,
+// or of one of its variants, costs UnitPrice each. Tiers with a customer
+// group apply only to customers in that group, e.g. contractors.
+type PriceTier struct {
+	Base
+	ProductID     uuid.UUID  `gorm:"not null;index" json:"product_id"`
+	VariantID     *uuid.UUID `gorm:"index" json:"variant_id,omitempty"`
+	CustomerGroup *string    `gorm:"index" json:"customer_group,omitempty"`
+	MinQuantity   float64    `gorm:"type:decimal(12,3);not null" json:"min_quantity"`
+	UnitPrice     float64    `gorm:"type:decimal(10,2);not null" json:"unit_price"`
+
+	// Relationships
+	Product *Product        `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+	Variant *ProductVariant `gorm:"foreignKey:VariantID" json:"variant,omitempty"`
+}
+
+// SalePricing schedules a temporary sale price for a product or variant.
+// Either end of the sale may be left open.
+type SalePricing struct {
+	SalePrice    *float64   `gorm:"type:decimal(10,2)" json:"sale_price"`
+	SaleStartsAt *time.Time `json:"sale_starts_at"`
+	SaleEndsAt   *time.Time `json:"sale_ends_at"`
+}
+
+// SaleActive reports whether the sale price applies at the given time
+func (s SalePricing) SaleActive(listPrice float64, at time.Time) bool {
+	if s.SalePrice == nil || *s.SalePrice >= listPrice {
+		return false
+	}
+	if s.SaleStartsAt != nil && at.Before(*s.SaleStartsAt) {
+		return false
+	}
+	if s.SaleEndsAt != nil && !at.Before(*s.SaleEndsAt) {
+		return false
+	}
+	return true
+}
+
+// PriceAt returns the price charged at the given time: the sale price while
+// a sale runs, otherwise the list price
+func (s SalePricing) PriceAt(listPrice float64, at time.Time) float64 {
+	if s.SaleActive(listPrice, at) {
+		return *s.SalePrice
+	}
+	return listPrice
+}
+
+// DisplayPrices are computed when a product or variant is loaded so catalog
+// responses show what a shopper pays now and, during a sale, the price it
+// is reduced from
+type DisplayPrices struct {
+	CurrentPrice   float64  `gorm:"-" json:"current_price"`
+	CompareAtPrice *float64 `gorm:"-" json:"compare_at_price,omitempty"`
+}
+
+// Set works out the display prices from the list price and sale as of now
+func (d *DisplayPrices) Set(listPrice float64, sale SalePricing) {
+	now := time.Now()
+	d.CurrentPrice = sale.PriceAt(listPrice, now)
+	d.CompareAtPrice = nil
+	if sale.SaleActive(listPrice, now) {
+		d.CompareAtPrice = &listPrice
+	}
+}
+
+// PriceChange is a snapshot of a product's or variant's prices, written each
+// time an admin changes the list price or the sale
+type PriceChange struct {
+	Base
+	ProductID   uuid.UUID  `gorm:"not null;index" json:"product_id"`
+	VariantID   *uuid.UUID `gorm:"index" json:"variant_id,omitempty"`
+	Price       float64    `gorm:"type:decimal(10,2);not null" json:"price"`
+	SalePricing
+	ChangedByID *uuid.UUID `json:"changed_by_id,omitempty"`
+	APIKeyID    *uuid.UUID `json:"api_key_id,omitempty"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSalePricingSaleActive(t *testing.T) {
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	end := time.Date(2026, 3, 8, 9, 0, 0, 0, time.UTC)
+	price := func(p float64) *float64 { return &p }
+
+	tests := []struct {
+		name      string
+		sale      SalePricing
+		listPrice float64
+		at        time.Time
+		want      bool
+	}{
+		{"no sale price", SalePricing{SaleStartsAt: &start, SaleEndsAt: &end}, 100, start, false},
+		{"sale price equals list", SalePricing{SalePrice: price(100)}, 100, start, false},
+		{"sale price above list", SalePricing{SalePrice: price(120)}, 100, start, false},
+		{"open-ended sale", SalePricing{SalePrice: price(80)}, 100, start, true},
+		{"before start", SalePricing{SalePrice: price(80), SaleStartsAt: &start, SaleEndsAt: &end}, 100, start.Add(-time.Nanosecond), false},
+		{"at start", SalePricing{SalePrice: price(80), SaleStartsAt: &start, SaleEndsAt: &end}, 100, start, true},
+		{"just before end", SalePricing{SalePrice: price(80), SaleStartsAt: &start, SaleEndsAt: &end}, 100, end.Add(-time.Nanosecond), true},
+		{"at end", SalePricing{SalePrice: price(80), SaleStartsAt: &start, SaleEndsAt: &end}, 100, end, false},
+		{"no start, before end", SalePricing{SalePrice: price(80), SaleEndsAt: &end}, 100, start.AddDate(-1, 0, 0), true},
+		{"no end, long after start", SalePricing{SalePrice: price(80), SaleStartsAt: &start}, 100, end.AddDate(1, 0, 0), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sale.SaleActive(tt.listPrice, tt.at); got != tt.want {
+				t.Errorf("SaleActive(%v, %v) = %v, want %v", tt.listPrice, tt.at, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSalePricingPriceAt(t *testing.T) {
+	start := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	salePrice := 80.0
+	sale := SalePricing{SalePrice: &salePrice, SaleStartsAt: &start}
+
+	if got := sale.PriceAt(100, start); got != 80 {
+		t.Errorf("PriceAt during sale = %v, want 80", got)
+	}
+	if got := sale.PriceAt(100, start.Add(-time.Second)); got != 100 {
+		t.Errorf("PriceAt before sale = %v, want 100", got)
+	}
+}
@@ -0,0 +1,34 @@
+package models
+
+import (
+	uuid "github.com/satori/go.uuid"
+)
+
+// RelatedProduct is a product often bought in the same order as another,
+// recomputed periodically from order history. Rank 1 is the strongest.
+type RelatedProduct struct {
+	Base
+	ProductID        uuid.UUID `gorm:"not null;uniqueIndex:idx_related_product_pair;index" json:"product_id"`
+	RelatedProductID uuid.UUID `gorm:"not null;uniqueIndex:idx_related_product_pair" json:"related_product_id"`
+	CoPurchases      int       `gorm:"not null" json:"co_purchases"`
+	Score            float64   `gorm:"not null" json:"score"`
+	Rank             int       `gorm:"not null" json:"rank"`
+}
+
+type RelatedOverrideType string
+
+const (
+	RelatedOverridePinned RelatedOverrideType = "pinned"
+	RelatedOverrideHidden RelatedOverrideType = "hidden"
+)
+
+// RelatedProductOverride is an admin's correction to a product's related
+// products: pinned products are always shown first, in position order, and
+// hidden ones are never shown however often they are bought together
+type RelatedProductOverride struct {
+	Base
+	ProductID        uuid.UUID           `gorm:"not null;uniqueIndex:idx_related_override_pair;index" json:"product_id"`
+	RelatedProductID uuid.UUID           `gorm:"not null;uniqueIndex:idx_related_override_pair" json:"related_product_id"`
+	Type             RelatedOverrideType `gorm:"not null" json:"type"`
+	Position         int                 `gorm:"not null;default:0" json:"position"`
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+type ReviewStatus string
+
+const (
+	ReviewStatusPending  ReviewStatus = "pending"
+	ReviewStatusApproved ReviewStatus = "approved"
+	ReviewStatusRejected ReviewStatus = "rejected"
+)
+
+const (
+	MinReviewRating = 1
+	MaxReviewRating = 5
+)
+
+// Review is a customer's rating of a product they bought and received. The
+// order item it cites proves the purchase. Reviews are hidden until a
+// moderator approves them, and go back to pending when edited.
+type Review struct {
+	Base
+	ProductID      uuid.UUID    `gorm:"not null;uniqueIndex:idx_review_user_product;index" json:"product_id"`
+	UserID         uuid.UUID    `gorm:"not null;uniqueIndex:idx_review_user_product" json:"user_id"`
+	OrderItemID    uuid.UUID    `gorm:"not null" json:"order_item_id"`
+	Rating         int          `gorm:"not null" json:"rating"`
+	Title          string       `json:"title"`
+	Body           string       `gorm:"type:text" json:"body"`
+	Photos         ReviewPhotos `gorm:"type:jsonb" json:"photos"`
+	Status         ReviewStatus `gorm:"not null;default:'pending';index" json:"status"`
+	ModerationNote string       `json:"moderation_note,omitempty"`
+	ModeratedByID  *uuid.UUID   `json:"moderated_by_id,omitempty"`
+	ModeratedAt    *time.Time   `json:"moderated_at,omitempty"`
+
+	// Relationships
+	Product *Product `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+	User    *User    `gorm:"foreignKey:UserID" json:"-"`
+}
+
+// ReviewPhoto is a photo uploaded to Cloudinary for a review. The public ID
+// is kept so the photo can be removed from Cloudinary again.
+type ReviewPhoto struct {
+	PublicID string `json:"public_id"`
+	URL      string `json:"url"`
+}
+
+type ReviewPhotos []ReviewPhoto
+
+func (rp ReviewPhotos) Value() (driver.Value, error) {
+	if rp == nil {
+		return json.Marshal([]ReviewPhoto{})
+	}
+	return json.Marshal(rp)
+}
+
+func (rp *ReviewPhotos) Scan(value interface{}) error {
+	if value == nil {
+		*rp = nil
+		return nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, rp)
+	case string:
+		return json.Unmarshal([]byte(v), rp)
+	default:
+		return errors.New("cannot scan ReviewPhotos")
+	}
+}
+
+// ProductRating is the average and count of a product's approved reviews,
+// kept on the product so listings can show it without aggregating
+type ProductRating struct {
+	RatingAverage float64 `gorm:"type:decimal(3,2);not null;default:0" json:"rating_average"`
+	RatingCount   int     `gorm:"not null;default:0" json:"rating_count"`
+}
+
+// PublicIDs returns the Cloudinary public IDs of the photos
+func (rp ReviewPhotos) PublicIDs() []string {
+	ids := make([]string, 0, len(rp))
+	for _, photo := range rp {
+		ids = append(ids, photo.PublicID)
+	}
+	return ids
+}
@@ -0,0 +1,132 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+type Permission string
+
+const (
+	PermissionAll             Permission = "*"
+	PermissionCategoriesRead  Permission = "categories:read"
+	PermissionCategoriesWrite Permission = "categories:write"
+	PermissionProductsRead    Permission = "products:read"
+	PermissionProductsWrite   Permission = "products:write"
+	PermissionReviewsModerate Permission = "reviews:moderate"
+	PermissionInventoryRead   Permission = "inventory:read"
+	PermissionInventoryUpdate Permission = "inventory:update"
+	PermissionOrdersRead      Permission = "orders:read"
+	PermissionOrdersUpdate    Permission = "orders:update_status"
+	PermissionServicesRead    Permission = "services:read"
+	PermissionServicesUpdate  Permission = "services:update"
+	PermissionServicesQuote   Permission = "services:quote"
+	PermissionUsersRead       Permission = "users:read"
+	PermissionUsersWrite      Permission = "users:write"
+	PermissionReportsRead     Permission = "reports:read"
+	PermissionRolesManage     Permission = "roles:manage"
+	PermissionAPIKeysManage   Permission = "api_keys:manage"
+)
+
+// AllPermissions lists every permission that can be granted to a role
+var AllPermissions = []Permission{
+	PermissionCategoriesRead,
+	PermissionCategoriesWrite,
+	PermissionProductsRead,
+	PermissionProductsWrite,
+	PermissionReviewsModerate,
+	PermissionInventoryRead,
+	PermissionInventoryUpdate,
+	PermissionOrdersRead,
+	PermissionOrdersUpdate,
+	PermissionServicesRead,
+	PermissionServicesUpdate,
+	PermissionServicesQuote,
+	PermissionUsersRead,
+	PermissionUsersWrite,
+	PermissionReportsRead,
+	PermissionRolesManage,
+	PermissionAPIKeysManage,
+}
+
+// DefaultRolePermissions are the permissions seeded for the built-in roles
+var DefaultRolePermissions = map[UserRole]PermissionList{
+	RoleCustomer: {},
+	RoleAdmin:    {PermissionAll},
+	RoleInventoryClerk: {
+		PermissionCategoriesRead,
+		PermissionProductsRead,
+		PermissionProductsWrite,
+		PermissionInventoryRead,
+		PermissionInventoryUpdate,
+	},
+	RoleDispatcher: {
+		PermissionOrdersRead,
+		PermissionOrdersUpdate,
+	},
+	RoleServiceTechnician: {
+		PermissionServicesRead,
+		PermissionServicesUpdate,
+	},
+	RoleAccountant: {
+		PermissionOrdersRead,
+		PermissionInventoryRead,
+		PermissionReportsRead,
+	},
+}
+
+// Role groups permissions under a name that users are assigned via User.Role
+type Role struct {
+	Base
+	Name        UserRole       `gorm:"uniqueIndex;not null" json:"name"`
+	Description string         `json:"description"`
+	Permissions PermissionList `gorm:"type:jsonb" json:"permissions"`
+	IsSystem    bool           `gorm:"default:false" json:"is_system"`
+}
+
+// HasPermission reports whether the role grants the permission
+func (r *Role) HasPermission(permission Permission) bool {
+	for _, granted := range r.Permissions {
+		if granted == PermissionAll || granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// IsValidPermission reports whether the permission is known
+func IsValidPermission(permission Permission) bool {
+	for _, known := range AllPermissions {
+		if known == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionList is a custom type for handling JSON array of permissions
+type PermissionList []Permission
+
+func (pl PermissionList) Value() (driver.Value, error) {
+	if pl == nil {
+		return json.Marshal([]Permission{})
+	}
+	return json.Marshal(pl)
+}
+
+func (pl *PermissionList) Scan(value interface{}) error {
+	if value == nil {
+		*pl = nil
+		return nil
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, pl)
+	case string:
+		return json.Unmarshal([]byte(v), pl)
+	default:
+		return errors.New("cannot scan PermissionList")
+	}
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+type SessionRevokeReason string
+
+const (
+	SessionRevokedLogout        SessionRevokeReason = "logout"
+	SessionRevokedPasswordReset SessionRevokeReason = "password_reset"
+	SessionRevokedAdmin         SessionRevokeReason = "admin_action"
+	SessionRevokedTokenReuse    SessionRevokeReason = "refresh_token_reuse"
+	SessionRevokedDeactivated   SessionRevokeReason = "account_deactivated"
+	SessionRevokedByUser        SessionRevokeReason = "user_revoked"
+	SessionRevokedErased        SessionRevokeReason = "account_erased"
+)
+
+// Session represents a login on a single device. Every refresh token issued
+// for the login belongs to the same session, which acts as the token family.
+type Session struct {
+	Base
+	UserID            uuid.UUID            `gorm:"not null;index" json:"user_id"`
+	UserAgent         string               `json:"user_agent"`
+	IPAddress         string               `json:"ip_address"`
+	LastSeenAt        time.Time            `gorm:"not null;default:CURRENT_TIMESTAMP" json:"last_seen_at"`
+	TwoFactorVerified bool                 `gorm:"default:false" json:"two_factor_verified"`
+	ExpiresAt         time.Time            `gorm:"not null" json:"expires_at"`
+	RevokedAt         *time.Time           `json:"revoked_at,omitempty"`
+	RevokedReason     *SessionRevokeReason `json:"revoked_reason,omitempty"`
+
+	// Relationships
+	User          User           `gorm:"foreignKey:UserID" json:"-"`
+	RefreshTokens []RefreshToken `gorm:"foreignKey:SessionID" json:"-"`
+}
+
+// RefreshToken is a single-use refresh token. Only the SHA-256 hash is stored.
+type RefreshToken struct {
+	Base
+	SessionID uuid.UUID  `gorm:"not null;index" json:"session_id"`
+	TokenHash string     `gorm:"uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+
+	// Relationships
+	Session Session `gorm:"foreignKey:SessionID" json:"-"`
+}
+
+// IsActive reports whether the session can still be used
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
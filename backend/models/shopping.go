@@ -22,12 +22,14 @@ type CartItem struct {
 	Base
 	CartID     uuid.UUID `gorm:"not null" json:"cart_id"`
 	ProductID  uuid.UUID `gorm:"not null" json:"product_id"`
-	Quantity   int       `gorm:"not null;default:1" json:"quantity"`
+	VariantID  *uuid.UUID `gorm:"index" json:"variant_id,omitempty"`
+	Quantity   float64   `gorm:"type:decimal(12,3);not null;default:1" json:"quantity"`
 	UnitPrice  float64   `gorm:"type:decimal(10,2);not null" json:"unit_price"`
 	
 	// Relationships
 	Cart    Cart    `gorm:"foreignKey:CartID" json:"cart,omitempty"`
 	Product Product `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+	Variant *ProductVariant `gorm:"foreignKey:VariantID" json:"variant,omitempty"`
 }
 
 type Wishlist struct {
@@ -69,12 +71,15 @@ type OrderItem struct {
 	Base
 	OrderID    uuid.UUID `gorm:"not null" json:"order_id"`
 	ProductID  uuid.UUID `gorm:"not null" json:"product_id"`
-	Quantity   int       `gorm:"not null" json:"quantity"`
+	VariantID  *uuid.UUID `gorm:"index" json:"variant_id,omitempty"`
+	Quantity   float64   `gorm:"type:decimal(12,3);not null" json:"quantity"`
 	UnitPrice  float64   `gorm:"type:decimal(10,2);not null" json:"unit_price"`
+	BundleDeductions BundleDeductions `gorm:"type:jsonb" json:"bundle_deductions,omitempty"`
 	
 	// Relationships
 	Order   Order   `gorm:"foreignKey:OrderID" json:"order,omitempty"`
 	Product Product `gorm:"foreignKey:ProductID" json:"product,omitempty"`
+	Variant *ProductVariant `gorm:"foreignKey:VariantID" json:"variant,omitempty"`
 }
 
 // AddressData is a custom type for handling address JSON in orders
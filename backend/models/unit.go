@@ -0,0 +1,104 @@
+package models
+
+import (
+	"fmt"
+	"math"
+)
+
+type UnitOfMeasure string
+
+const (
+	UnitPiece    UnitOfMeasure = "piece"
+	UnitMetre    UnitOfMeasure = "metre"
+	UnitKilogram UnitOfMeasure = "kg"
+	UnitLitre    UnitOfMeasure = "litre"
+	UnitBox      UnitOfMeasure = "box"
+)
+
+// quantityPrecision is the number of decimal places quantities are kept to
+const quantityPrecision = 1000
+
+// Measure describes how a product is sold and restocked. Quantities in carts,
+// orders and stock are in the sales unit, in multiples of QuantityStep, e.g.
+// cable by the metre in 0.5 m steps or nails by the box of 100. Stock bought
+// in a purchase unit, such as a 100 m drum, converts at PurchaseUnitSize
+// sales units each.
+type Measure struct {
+	Unit             UnitOfMeasure `gorm:"not null;default:'piece'" json:"unit"`
+	UnitSize         float64       `gorm:"type:decimal(12,3);not null;default:1" json:"unit_size"` // pieces per box
+	QuantityStep     float64       `gorm:"type:decimal(12,3);not null;default:1" json:"quantity_step"`
+	PurchaseUnit     string        `json:"purchase_unit,omitempty"`
+	PurchaseUnitSize float64       `gorm:"type:decimal(12,3);not null;default:1" json:"purchase_unit_size"`
+}
+
+// IsValidUnit reports whether unit is a known unit of measure
+func IsValidUnit(unit UnitOfMeasure) bool {
+	switch unit {
+	case UnitPiece, UnitMetre, UnitKilogram, UnitLitre, UnitBox:
+		return true
+	}
+	return false
+}
+
+// IsCountable reports whether the unit can only be sold whole
+func (m Measure) IsCountable() bool {
+	return m.Unit == UnitPiece || m.Unit == UnitBox || m.Unit == ""
+}
+
+// Validate checks that the measure is self-consistent
+func (m Measure) Validate() error {
+	if !IsValidUnit(m.Unit) {
+		return fmt.Errorf("unit must be piece, metre, kg, litre or box")
+	}
+	if m.QuantityStep <= 0 {
+		return fmt.Errorf("quantity_step must be greater than 0")
+	}
+	if m.IsCountable() && !isWhole(m.QuantityStep) {
+		return fmt.Errorf("quantity_step must be a whole number for %s", m.Unit)
+	}
+	if m.Unit == UnitBox && (m.UnitSize < 1 || !isWhole(m.UnitSize)) {
+		return fmt.Errorf("unit_size must be the whole number of pieces in a box")
+	}
+	if m.PurchaseUnitSize <= 0 {
+		return fmt.Errorf("purchase_unit_size must be greater than 0")
+	}
+	return nil
+}
+
+// ValidateQuantity checks a quantity being bought against the allowed steps
+func (m Measure) ValidateQuantity(quantity float64) error {
+	// Quantities are stored rounded, so one too small to store is zero
+	quantity = RoundQuantity(quantity)
+	if quantity <= 0 {
+		return fmt.Errorf("quantity must be greater than 0")
+	}
+
+	step := m.QuantityStep
+	if step <= 0 {
+		step = 1
+	}
+
+	steps := quantity / step
+	if math.Abs(steps-math.Round(steps)) > 1e-6 {
+		return fmt.Errorf("quantity must be a multiple of %g %s", step, m.Unit)
+	}
+	return nil
+}
+
+// FromPurchaseUnits converts a quantity in purchase units to sales units
+func (m Measure) FromPurchaseUnits(quantity float64) float64 {
+	size := m.PurchaseUnitSize
+	if size <= 0 {
+		size = 1
+	}
+	return RoundQuantity(quantity * size)
+}
+
+// RoundQuantity drops floating point noise beyond the stored precision
+func RoundQuantity(quantity float64) float64 {
+	return math.Round(quantity*quantityPrecision) / quantityPrecision
+}
+
+func isWhole(value float64) bool {
+	return value == math.Trunc(value)
+}
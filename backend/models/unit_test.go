@@ -0,0 +1,85 @@
+package models
+
+import "testing"
+
+func TestRoundQuantity(t *testing.T) {
+	tests := []struct {
+		name     string
+		quantity float64
+		want     float64
+	}{
+		{"float noise", 0.1 + 0.2, 0.3},
+		{"rounds half up", 1.2345, 1.235},
+		{"drops below precision", 2.0004, 2},
+		{"whole", 7, 7},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RoundQuantity(tt.quantity); got != tt.want {
+				t.Errorf("RoundQuantity(%v) = %v, want %v", tt.quantity, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMeasureValidateQuantity(t *testing.T) {
+	cable := Measure{Unit: UnitMetre, QuantityStep: 0.1}
+	halfMetre := Measure{Unit: UnitMetre, QuantityStep: 0.5}
+	box := Measure{Unit: UnitBox, UnitSize: 100, QuantityStep: 1}
+
+	tests := []struct {
+		name     string
+		measure  Measure
+		quantity float64
+		wantErr  bool
+	}{
+		{"cable single step", cable, 0.1, false},
+		{"cable summed steps", cable, 0.1 + 0.2, false},
+		{"cable awkward multiple", cable, 0.7, false},
+		{"cable long run", cable, 123.4, false},
+		{"cable between steps", cable, 2.35, true},
+		{"half metre multiple", halfMetre, 1.5, false},
+		{"half metre between steps", halfMetre, 1.25, true},
+		{"whole boxes", box, 3, false},
+		{"part of a box", box, 2.5, true},
+		{"noise below precision", box, 1.0001, false},
+		{"zero", cable, 0, true},
+		{"negative", cable, -0.5, true},
+		{"rounds to zero", cable, 0.0004, true},
+		{"missing step counts whole units", Measure{Unit: UnitPiece}, 2, false},
+		{"missing step rejects fractions", Measure{Unit: UnitPiece}, 1.5, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.measure.ValidateQuantity(tt.quantity)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQuantity(%v) error = %v, wantErr %v", tt.quantity, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMeasureFromPurchaseUnits(t *testing.T) {
+	tests := []struct {
+		name     string
+		measure  Measure
+		quantity float64
+		want     float64
+	}{
+		{"drums of cable", Measure{Unit: UnitMetre, PurchaseUnitSize: 100}, 2.5, 250},
+		{"tenths", Measure{Unit: UnitMetre, PurchaseUnitSize: 0.1}, 3, 0.3},
+		{"rounded to precision", Measure{Unit: UnitKilogram, PurchaseUnitSize: 0.333}, 3, 0.999},
+		{"cartons of boxes", Measure{Unit: UnitBox, UnitSize: 100, PurchaseUnitSize: 12}, 5, 60},
+		{"missing size is one to one", Measure{Unit: UnitPiece}, 4, 4},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.measure.FromPurchaseUnits(tt.quantity); got != tt.want {
+				t.Errorf("FromPurchaseUnits(%v) = %v, want %v", tt.quantity, got, tt.want)
+			}
+		})
+	}
+}
@@ -8,8 +8,12 @@ import (
 type UserRole string
 
 const (
-	RoleCustomer UserRole = "customer"
-	RoleAdmin    UserRole = "admin"
+	RoleCustomer          UserRole = "customer"
+	RoleAdmin             UserRole = "admin"
+	RoleInventoryClerk    UserRole = "inventory_clerk"
+	RoleDispatcher        UserRole = "dispatcher"
+	RoleServiceTechnician UserRole = "service_technician"
+	RoleAccountant        UserRole = "accountant"
 )
 
 type User struct {
@@ -20,8 +24,16 @@ type User struct {
 	FullName        string     `gorm:"not null" json:"full_name"`
 	Role            UserRole   `gorm:"not null;default:'customer'" json:"role"`
 	IsActive        bool       `gorm:"default:true" json:"is_active"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	PhoneVerifiedAt *time.Time `json:"phone_verified_at"`
+	LockedUntil     *time.Time `gorm:"index" json:"locked_until"`
 	ResetToken      *string    `gorm:"index" json:"-"`
 	ResetTokenExpiry *time.Time `json:"-"`
+	TwoFactorEnabled  bool       `gorm:"default:false" json:"two_factor_enabled"`
+	TwoFactorSecret   *string    `json:"-"`
+	TwoFactorLastStep int64      `gorm:"default:0" json:"-"`
+	ErasedAt          *time.Time `json:"erased_at,omitempty"`
+	CustomerGroup     *string    `gorm:"index" json:"customer_group,omitempty"`
 	
 	// Relationships
 	Addresses       []Address      `gorm:"foreignKey:UserID" json:"addresses,omitempty"`
@@ -44,3 +56,34 @@ type Address struct {
 	// Relationships
 	User User `gorm:"foreignKey:UserID" json:"user,omitempty"`
 }
+
+// IsEmailVerified reports whether the user has confirmed their email address
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// IsPhoneVerified reports whether the user has confirmed their phone number
+func (u *User) IsPhoneVerified() bool {
+	return u.Phone != nil && *u.Phone != "" && u.PhoneVerifiedAt != nil
+}
+
+// IsErased reports whether the user's personal data has been anonymized
+func (u *User) IsErased() bool {
+	return u.ErasedAt != nil
+}
+
+// IsLocked reports whether the account is temporarily locked after failed logins
+func (u *User) IsLocked() bool {
+	return u.LockedUntil != nil && time.Now().Before(*u.LockedUntil)
+}
+
+// RecoveryCode is a single-use two-factor backup code, stored as an Argon2id hash
+type RecoveryCode struct {
+	Base
+	UserID   uuid.UUID  `gorm:"not null;index" json:"user_id"`
+	CodeHash string     `gorm:"not null" json:"-"`
+	UsedAt   *time.Time `json:"used_at,omitempty"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+type VerificationType string
+
+const (
+	VerificationTypeEmail VerificationType = "email"
+	VerificationTypePhone VerificationType = "phone"
+)
+
+// VerificationCode proves ownership of an email address or phone number.
+// Email verification uses a long link token, phone verification a short OTP;
+// either way only the SHA-256 hash is stored.
+type VerificationCode struct {
+	Base
+	UserID     uuid.UUID        `gorm:"not null;index" json:"user_id"`
+	Type       VerificationType `gorm:"not null" json:"type"`
+	Target     string           `gorm:"not null" json:"target"`
+	CodeHash   string           `gorm:"not null;index" json:"-"`
+	ExpiresAt  time.Time        `gorm:"not null" json:"expires_at"`
+	Attempts   int              `gorm:"not null;default:0" json:"attempts"`
+	ConsumedAt *time.Time       `json:"consumed_at,omitempty"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID" json:"-"`
+}
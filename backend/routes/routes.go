@@ -3,6 +3,7 @@ package routes
 import (
 	"backend/handlers"
 	"backend/middleware"
+	"backend/models"
 
 	"github.com/gofiber/fiber/v2"
 )
@@ -16,6 +17,10 @@ func SetupRoutes(app *fiber.App) {
 	app.Get("/health/services", handlers.ExternalServicesHealthCheck)
 	app.Get("/health/full", handlers.FullHealthCheck)
 
+	// Sitemaps for search engines
+	app.Get("/sitemap.xml", handlers.Sitemap)
+	app.Get("/sitemap-:page.xml", handlers.SitemapPage)
+
 	// API routes
 	api := app.Group("/api")
 
@@ -29,7 +34,14 @@ func SetupRoutes(app *fiber.App) {
 		{
 			auth.Post("/register", handlers.Register)
 			auth.Post("/login", handlers.Login)
-			auth.Post("/logout", handlers.Logout)
+			auth.Post("/otp/request", handlers.RequestLoginOTP)
+			auth.Post("/otp/verify", handlers.VerifyLoginOTP)
+			auth.Post("/refresh", handlers.RefreshToken)
+			auth.Post("/2fa/verify", handlers.VerifyTwoFactorLogin)
+			auth.Post("/verify-email", handlers.VerifyEmail)
+			auth.Post("/unlock", handlers.UnlockAccount)
+			auth.Post("/verify-phone", middleware.AuthMiddleware(), handlers.VerifyPhone)
+			auth.Post("/logout", middleware.AuthMiddleware(), handlers.Logout)
 			auth.Post("/password/reset", handlers.RequestPasswordReset)
 			auth.Post("/password/reset/confirm", handlers.ConfirmPasswordReset)
 		}
@@ -37,10 +49,15 @@ func SetupRoutes(app *fiber.App) {
 		// Public catalog routes
 		catalog := api.Group("/catalog")
 		{
-			catalog.Get("/categories", handlers.GetCategories)
-			catalog.Get("/products", handlers.GetProducts)
-			catalog.Get("/products/:slug", handlers.GetProductDetails)
+			catalog.Get("/categories", middleware.CatalogCache(), handlers.GetCategories)
+			catalog.Get("/brands", handlers.GetBrands)
+			catalog.Get("/brands/:slug", handlers.GetBrandPage)
+			catalog.Get("/products", middleware.CatalogCache(handlers.ProductListParams...), handlers.GetProducts)
+			catalog.Get("/products/:slug", middleware.CatalogCache(), handlers.GetProductDetails)
+			catalog.Get("/products/:slug/reviews", handlers.GetProductReviews)
+			catalog.Get("/products/:slug/structured-data", handlers.GetProductStructuredData)
 			catalog.Get("/search", handlers.SearchProducts)
+			catalog.Get("/suggest", handlers.SuggestProducts)
 		}
 
 		// Protected routes
@@ -50,6 +67,23 @@ func SetupRoutes(app *fiber.App) {
 			// User profile
 			protected.Get("/profile", handlers.GetProfile)
 			protected.Put("/profile", handlers.UpdateProfile)
+			protected.Get("/profile/export", handlers.ExportUserData)
+			protected.Post("/profile/erase", handlers.EraseAccount)
+			protected.Get("/profile/sessions", handlers.GetUserSessions)
+			protected.Delete("/profile/sessions", handlers.RevokeOtherUserSessions)
+			protected.Delete("/profile/sessions/:id", handlers.RevokeUserSession)
+			protected.Post("/profile/verify-email/resend", handlers.ResendEmailVerification)
+			protected.Post("/profile/verify-phone/send", handlers.SendPhoneVerification)
+
+			// Two-factor authentication
+			twoFactor := protected.Group("/profile/2fa")
+			{
+				twoFactor.Get("", handlers.GetTwoFactorStatus)
+				twoFactor.Post("/setup", handlers.SetupTwoFactor)
+				twoFactor.Post("/enable", handlers.EnableTwoFactor)
+				twoFactor.Post("/disable", handlers.DisableTwoFactor)
+				twoFactor.Post("/recovery-codes", handlers.RegenerateRecoveryCodes)
+			}
 
 			// Cart routes
 			cart := protected.Group("/cart")
@@ -61,6 +95,17 @@ func SetupRoutes(app *fiber.App) {
 				cart.Delete("", handlers.ClearCart)
 			}
 
+			// Review routes
+			reviews := protected.Group("/reviews")
+			{
+				reviews.Get("", handlers.GetUserReviews)
+				reviews.Post("", handlers.CreateReview)
+				reviews.Put("/:id", middleware.InvalidateCatalogCache(), handlers.UpdateReview)
+				reviews.Delete("/:id", middleware.InvalidateCatalogCache(), handlers.DeleteReview)
+				reviews.Post("/:id/photos", middleware.InvalidateCatalogCache(), handlers.AddReviewPhotos)
+				reviews.Delete("/:id/photos", middleware.InvalidateCatalogCache(), handlers.DeleteReviewPhoto)
+			}
+
 			// Wishlist routes
 			wishlist := protected.Group("/wishlist")
 			{
@@ -72,7 +117,7 @@ func SetupRoutes(app *fiber.App) {
 			// Checkout routes
 			checkout := protected.Group("/checkout")
 			{
-				checkout.Post("/place", handlers.PlaceOrder)
+				checkout.Post("/place", middleware.VerifiedEmailMiddleware(), handlers.PlaceOrder)
 				checkout.Get("/shipping-options", handlers.GetShippingOptions)
 			}
 
@@ -81,7 +126,7 @@ func SetupRoutes(app *fiber.App) {
 			{
 				orders.Get("", handlers.GetUserOrders)
 				orders.Get("/:id", handlers.GetOrderDetails)
-				orders.Post("", handlers.CreateOrder)
+				orders.Post("", middleware.VerifiedEmailMiddleware(), handlers.CreateOrder)
 				orders.Post("/:id/cancel", handlers.CancelOrder)
 			}
 
@@ -120,45 +165,98 @@ func SetupRoutes(app *fiber.App) {
 			}
 		}
 
-		// Admin routes
+		// Admin routes, gated per route by role permissions or API key scopes
 		admin := api.Group("/admin")
-		admin.Use(middleware.AuthMiddleware(), middleware.AdminMiddleware())
+		admin.Use(middleware.APIKeyOrAuthMiddleware())
 		{
 			// Categories management
-			admin.Get("/categories", handlers.AdminGetCategories)
-			admin.Post("/categories", handlers.AdminCreateCategory)
-			admin.Put("/categories/:id", handlers.AdminUpdateCategory)
-			admin.Delete("/categories/:id", handlers.AdminDeleteCategory)
+			admin.Get("/categories", middleware.RequirePermission(models.PermissionCategoriesRead), handlers.AdminGetCategories)
+			admin.Post("/categories", middleware.RequirePermission(models.PermissionCategoriesWrite), middleware.InvalidateCatalogCache(), handlers.AdminCreateCategory)
+			admin.Put("/categories/:id", middleware.RequirePermission(models.PermissionCategoriesWrite), middleware.InvalidateCatalogCache(), handlers.AdminUpdateCategory)
+			admin.Delete("/categories/:id", middleware.RequirePermission(models.PermissionCategoriesWrite), middleware.InvalidateCatalogCache(), handlers.AdminDeleteCategory)
+			admin.Get("/categories/:id/attributes", middleware.RequirePermission(models.PermissionCategoriesRead), handlers.AdminGetCategoryAttributes)
+			admin.Post("/categories/:id/attributes", middleware.RequirePermission(models.PermissionCategoriesWrite), middleware.InvalidateCatalogCache(), handlers.AdminCreateCategoryAttribute)
+			admin.Put("/attributes/:id", middleware.RequirePermission(models.PermissionCategoriesWrite), middleware.InvalidateCatalogCache(), handlers.AdminUpdateAttribute)
+			admin.Delete("/attributes/:id", middleware.RequirePermission(models.PermissionCategoriesWrite), middleware.InvalidateCatalogCache(), handlers.AdminDeleteAttribute)
+
+			// Brands management
+			admin.Get("/brands", middleware.RequirePermission(models.PermissionCategoriesRead), handlers.AdminGetBrands)
+			admin.Post("/brands", middleware.RequirePermission(models.PermissionCategoriesWrite), middleware.InvalidateCatalogCache(), handlers.AdminCreateBrand)
+			admin.Put("/brands/:id", middleware.RequirePermission(models.PermissionCategoriesWrite), middleware.InvalidateCatalogCache(), handlers.AdminUpdateBrand)
+			admin.Delete("/brands/:id", middleware.RequirePermission(models.PermissionCategoriesWrite), middleware.InvalidateCatalogCache(), handlers.AdminDeleteBrand)
 
 			// Products management
-			admin.Get("/products", handlers.AdminGetProducts)
-			admin.Post("/products", handlers.AdminCreateProduct)
-			admin.Put("/products/:id", handlers.AdminUpdateProduct)
-			admin.Delete("/products/:id", handlers.AdminDeleteProduct)
+			admin.Get("/products", middleware.RequirePermission(models.PermissionProductsRead), handlers.AdminGetProducts)
+			admin.Get("/products/export", middleware.RequirePermission(models.PermissionProductsRead), handlers.AdminExportProducts)
+			admin.Post("/products/import", middleware.RequirePermission(models.PermissionProductsWrite), handlers.AdminImportProducts)
+			admin.Get("/products/imports", middleware.RequirePermission(models.PermissionProductsRead), handlers.AdminGetProductImports)
+			admin.Get("/products/imports/:id", middleware.RequirePermission(models.PermissionProductsRead), handlers.AdminGetProductImport)
+			admin.Post("/products", middleware.RequirePermission(models.PermissionProductsWrite), middleware.InvalidateCatalogCache(), handlers.AdminCreateProduct)
+			admin.Put("/products/:id", middleware.RequirePermission(models.PermissionProductsWrite), middleware.InvalidateCatalogCache(), handlers.AdminUpdateProduct)
+			admin.Delete("/products/:id", middleware.RequirePermission(models.PermissionProductsWrite), middleware.InvalidateCatalogCache(), handlers.AdminDeleteProduct)
+			admin.Get("/products/:id/variants", middleware.RequirePermission(models.PermissionProductsRead), handlers.AdminGetProductVariants)
+			admin.Post("/products/:id/variants", middleware.RequirePermission(models.PermissionProductsWrite), middleware.InvalidateCatalogCache(), handlers.AdminCreateProductVariant)
+			admin.Put("/products/:id/variants/:variantId", middleware.RequirePermission(models.PermissionProductsWrite), middleware.InvalidateCatalogCache(), handlers.AdminUpdateProductVariant)
+			admin.Delete("/products/:id/variants/:variantId", middleware.RequirePermission(models.PermissionProductsWrite), middleware.InvalidateCatalogCache(), handlers.AdminDeleteProductVariant)
+			admin.Get("/products/:id/bundle-items", middleware.RequirePermission(models.PermissionProductsRead), handlers.AdminGetBundleItems)
+			admin.Put("/products/:id/bundle-items", middleware.RequirePermission(models.PermissionProductsWrite), middleware.InvalidateCatalogCache(), handlers.AdminSetBundleItems)
+			admin.Put("/products/:id/attributes", middleware.RequirePermission(models.PermissionProductsWrite), middleware.InvalidateCatalogCache(), handlers.AdminSetProductAttributes)
+			admin.Get("/products/:id/price-tiers", middleware.RequirePermission(models.PermissionProductsRead), handlers.AdminGetPriceTiers)
+			admin.Post("/products/:id/price-tiers", middleware.RequirePermission(models.PermissionProductsWrite), middleware.InvalidateCatalogCache(), handlers.AdminCreatePriceTier)
+			admin.Put("/products/:id/price-tiers/:tierId", middleware.RequirePermission(models.PermissionProductsWrite), middleware.InvalidateCatalogCache(), handlers.AdminUpdatePriceTier)
+			admin.Delete("/products/:id/price-tiers/:tierId", middleware.RequirePermission(models.PermissionProductsWrite), middleware.InvalidateCatalogCache(), handlers.AdminDeletePriceTier)
+			admin.Put("/products/:id/sale", middleware.RequirePermission(models.PermissionProductsWrite), middleware.InvalidateCatalogCache(), handlers.AdminSetProductSale)
+			admin.Delete("/products/:id/sale", middleware.RequirePermission(models.PermissionProductsWrite), middleware.InvalidateCatalogCache(), handlers.AdminDeleteProductSale)
+			admin.Put("/products/:id/variants/:variantId/sale", middleware.RequirePermission(models.PermissionProductsWrite), middleware.InvalidateCatalogCache(), handlers.AdminSetVariantSale)
+			admin.Delete("/products/:id/variants/:variantId/sale", middleware.RequirePermission(models.PermissionProductsWrite), middleware.InvalidateCatalogCache(), handlers.AdminDeleteVariantSale)
+			admin.Get("/products/:id/price-history", middleware.RequirePermission(models.PermissionProductsRead), handlers.AdminGetPriceHistory)
+			admin.Get("/products/:id/related", middleware.RequirePermission(models.PermissionProductsRead), handlers.AdminGetRelatedProducts)
+			admin.Put("/products/:id/related", middleware.RequirePermission(models.PermissionProductsWrite), middleware.InvalidateCatalogCache(), handlers.AdminSetRelatedProducts)
+			admin.Post("/recommendations/refresh", middleware.RequirePermission(models.PermissionProductsWrite), handlers.AdminRefreshRelatedProducts)
+
+			// Review moderation
+			admin.Get("/reviews", middleware.RequirePermission(models.PermissionReviewsModerate), handlers.AdminGetReviews)
+			admin.Put("/reviews/:id/moderate", middleware.RequirePermission(models.PermissionReviewsModerate), middleware.InvalidateCatalogCache(), handlers.AdminModerateReview)
+			admin.Delete("/reviews/:id", middleware.RequirePermission(models.PermissionReviewsModerate), middleware.InvalidateCatalogCache(), handlers.AdminDeleteReview)
 
 			// Inventory management
-			admin.Put("/inventory/stock", handlers.AdminUpdateStock)
-			admin.Get("/inventory/low-stock", handlers.AdminGetLowStockItems)
+			admin.Put("/inventory/stock", middleware.RequirePermission(models.PermissionInventoryUpdate), middleware.InvalidateCatalogCache(), handlers.AdminUpdateStock)
+			admin.Get("/inventory/low-stock", middleware.RequirePermission(models.PermissionInventoryRead), handlers.AdminGetLowStockItems)
 
 			// Orders management
-			admin.Get("/orders", handlers.AdminGetOrders)
-			admin.Put("/orders/:id/status", handlers.AdminUpdateOrderStatus)
-			admin.Get("/orders/:id", handlers.AdminGetOrderDetails)
+			admin.Get("/orders", middleware.RequirePermission(models.PermissionOrdersRead), handlers.AdminGetOrders)
+			admin.Put("/orders/:id/status", middleware.RequirePermission(models.PermissionOrdersUpdate), middleware.InvalidateCatalogCache(), handlers.AdminUpdateOrderStatus)
+			admin.Get("/orders/:id", middleware.RequirePermission(models.PermissionOrdersRead), handlers.AdminGetOrderDetails)
 
 			// Service management
-			admin.Get("/services/requests", handlers.AdminGetServiceRequests)
-			admin.Put("/services/requests/:id/status", handlers.AdminUpdateServiceStatus)
-			admin.Post("/services/requests/:id/quote", handlers.AdminCreateServiceQuote)
+			admin.Get("/services/requests", middleware.RequirePermission(models.PermissionServicesRead), handlers.AdminGetServiceRequests)
+			admin.Put("/services/requests/:id/status", middleware.RequirePermission(models.PermissionServicesUpdate), handlers.AdminUpdateServiceStatus)
+			admin.Post("/services/requests/:id/quote", middleware.RequirePermission(models.PermissionServicesQuote), handlers.AdminCreateServiceQuote)
 
 			// User management
-			admin.Get("/users", handlers.AdminGetUsers)
-			admin.Put("/users/:id/role", handlers.AdminUpdateUserRole)
-			admin.Delete("/users/:id", handlers.AdminDeleteUser)
+			admin.Get("/users", middleware.RequirePermission(models.PermissionUsersRead), handlers.AdminGetUsers)
+			admin.Put("/users/:id/role", middleware.RequirePermission(models.PermissionRolesManage), handlers.AdminUpdateUserRole)
+			admin.Put("/users/:id/customer-group", middleware.RequirePermission(models.PermissionUsersWrite), handlers.AdminUpdateUserCustomerGroup)
+			admin.Delete("/users/:id", middleware.RequirePermission(models.PermissionUsersWrite), handlers.AdminDeleteUser)
+			admin.Post("/users/:id/sessions/revoke", middleware.RequirePermission(models.PermissionUsersWrite), handlers.AdminRevokeUserSessions)
+			admin.Post("/users/:id/unlock", middleware.RequirePermission(models.PermissionUsersWrite), handlers.AdminUnlockUser)
+
+			// Roles and permissions
+			admin.Get("/permissions", middleware.RequirePermission(models.PermissionRolesManage), handlers.AdminGetPermissions)
+			admin.Get("/roles", middleware.RequirePermission(models.PermissionRolesManage), handlers.AdminGetRoles)
+			admin.Post("/roles", middleware.RequirePermission(models.PermissionRolesManage), handlers.AdminCreateRole)
+			admin.Put("/roles/:id", middleware.RequirePermission(models.PermissionRolesManage), handlers.AdminUpdateRole)
+			admin.Delete("/roles/:id", middleware.RequirePermission(models.PermissionRolesManage), handlers.AdminDeleteRole)
+
+			// API keys
+			admin.Get("/api-keys", middleware.RequirePermission(models.PermissionAPIKeysManage), handlers.AdminGetAPIKeys)
+			admin.Post("/api-keys", middleware.RequirePermission(models.PermissionAPIKeysManage), handlers.AdminCreateAPIKey)
+			admin.Delete("/api-keys/:id", middleware.RequirePermission(models.PermissionAPIKeysManage), handlers.AdminRevokeAPIKey)
 
 			// Reports
-			admin.Get("/reports/sales", handlers.AdminGetSalesReport)
-			admin.Get("/reports/inventory", handlers.AdminGetInventoryReport)
-			admin.Get("/reports/users", handlers.AdminGetUsersReport)
+			admin.Get("/reports/sales", middleware.RequirePermission(models.PermissionReportsRead), handlers.AdminGetSalesReport)
+			admin.Get("/reports/inventory", middleware.RequirePermission(models.PermissionReportsRead, models.PermissionInventoryRead), handlers.AdminGetInventoryReport)
+			admin.Get("/reports/users", middleware.RequirePermission(models.PermissionReportsRead), handlers.AdminGetUsersReport)
 		}
 	}
 }
@@ -4,6 +4,7 @@ import (
 	"backend/config"
 	"backend/models"
 	"log"
+	"time"
 )
 
 // SeedDatabase populates the database with initial data
@@ -16,6 +17,9 @@ func SeedDatabase() {
 	// Seed products
 	seedProducts(categories)
 
+	// Seed built-in roles
+	seedRoles()
+
 	// Seed admin user
 	seedAdminUser()
 
@@ -111,6 +115,38 @@ func seedProducts(categories []models.Category) {
 	}
 }
 
+func seedRoles() {
+	descriptions := map[models.UserRole]string{
+		models.RoleCustomer:          "Shopper with no back-office access",
+		models.RoleAdmin:             "Full access to every admin feature",
+		models.RoleInventoryClerk:    "Maintains products and stock levels",
+		models.RoleDispatcher:        "Fulfils orders and updates their status",
+		models.RoleServiceTechnician: "Handles service requests",
+		models.RoleAccountant:        "Reviews orders and financial reports",
+	}
+
+	for name, permissions := range models.DefaultRolePermissions {
+		// Existing roles keep any permissions an admin has since changed
+		var existing models.Role
+		if err := config.DB.Where("name = ?", name).First(&existing).Error; err == nil {
+			continue
+		}
+
+		role := models.Role{
+			Name:        name,
+			Description: descriptions[name],
+			Permissions: permissions,
+			IsSystem:    true,
+		}
+
+		if err := config.DB.Create(&role).Error; err != nil {
+			log.Printf("Error creating role %s: %v", name, err)
+		} else {
+			log.Printf("Created role: %s", name)
+		}
+	}
+}
+
 func seedAdminUser() {
 	// Check if admin already exists
 	var existingAdmin models.User
@@ -120,13 +156,16 @@ func seedAdminUser() {
 	}
 
 	// Create admin user
+	now := time.Now()
 	adminUser := models.User{
-		Email:        "admin@hardware.com",
-		PasswordHash: "$argon2id$v=19$m=65536,t=1,p=4$hardware-store-salt$admin123", // This should be properly hashed
-		FullName:     "Admin User",
-		Phone:        stringPtr("+254741594147"),
-		Role:         models.RoleAdmin,
-		IsActive:     true,
+		Email:           "admin@hardware.com",
+		PasswordHash:    "$argon2id$v=19$m=65536,t=1,p=4$hardware-store-salt$admin123", // This should be properly hashed
+		FullName:        "Admin User",
+		Phone:           stringPtr("+254741594147"),
+		Role:            models.RoleAdmin,
+		IsActive:        true,
+		EmailVerifiedAt: &now,
+		PhoneVerifiedAt: &now,
 	}
 
 	if err := config.DB.Create(&adminUser).Error; err != nil {
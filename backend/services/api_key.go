@@ -0,0 +1,188 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/config"
+	"backend/models"
+	"backend/utils"
+)
+
+const (
+	// apiKeyPrefix marks API keys so they can be told apart from JWTs
+	apiKeyPrefix = "hsk_"
+	// apiKeyTouchInterval limits how often last-used timestamps are written
+	apiKeyTouchInterval = time.Minute
+)
+
+var (
+	ErrInvalidAPIKey       = errors.New("invalid, expired or revoked API key")
+	ErrAPIKeyNotFound      = errors.New("API key not found")
+	ErrAPIKeyScopeDenied   = errors.New("scope cannot be granted to an API key")
+	ErrAPIKeyScopeRequired = errors.New("at least one scope is required")
+)
+
+// apiKeyForbiddenScopes can only be exercised by a signed-in human
+var apiKeyForbiddenScopes = map[models.Permission]bool{
+	models.PermissionRolesManage:   true,
+	models.PermissionAPIKeysManage: true,
+	models.PermissionUsersWrite:    true,
+}
+
+type APIKeyService struct {
+	redis *RedisService
+}
+
+func NewAPIKeyService() *APIKeyService {
+	return &APIKeyService{
+		redis: SharedRedisService(),
+	}
+}
+
+// DefaultAPIKeyRateLimit returns the per-minute request limit for keys created without one
+func DefaultAPIKeyRateLimit() int {
+	limit, err := strconv.Atoi(os.Getenv("API_KEY_RATE_LIMIT"))
+	if err != nil || limit <= 0 {
+		return 60
+	}
+	return limit
+}
+
+// IsAPIKey reports whether the credential looks like an API key rather than a JWT
+func IsAPIKey(credential string) bool {
+	return strings.HasPrefix(credential, apiKeyPrefix)
+}
+
+// CreateKey issues a new API key. The raw key is returned once and never stored.
+// Scopes are limited to permissions the issuer's role grants.
+func (a *APIKeyService) CreateKey(issuer *models.User, name string, scopes []models.Permission, rateLimit int, expiresAt *time.Time) (*models.APIKey, string, error) {
+	if len(scopes) == 0 {
+		return nil, "", ErrAPIKeyScopeRequired
+	}
+
+	if err := validatePermissions(scopes); err != nil {
+		return nil, "", err
+	}
+
+	role, err := NewRoleService().GetRole(string(issuer.Role))
+	if err != nil {
+		return nil, "", err
+	}
+
+	for _, scope := range scopes {
+		if apiKeyForbiddenScopes[scope] || !role.HasPermission(scope) {
+			return nil, "", fmt.Errorf("%w: %s", ErrAPIKeyScopeDenied, scope)
+		}
+	}
+
+	if rateLimit <= 0 {
+		rateLimit = DefaultAPIKeyRateLimit()
+	}
+
+	secret, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	rawKey := apiKeyPrefix + secret
+
+	key := models.APIKey{
+		Name:        name,
+		Prefix:      rawKey[:len(apiKeyPrefix)+8],
+		KeyHash:     utils.HashToken(rawKey),
+		Scopes:      scopes,
+		RateLimit:   rateLimit,
+		ExpiresAt:   expiresAt,
+		CreatedByID: issuer.ID,
+	}
+
+	if err := config.DB.Create(&key).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return &key, rawKey, nil
+}
+
+// Authenticate resolves a raw API key to an active key record
+func (a *APIKeyService) Authenticate(rawKey string) (*models.APIKey, error) {
+	if !IsAPIKey(rawKey) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	var key models.APIKey
+	if err := config.DB.Where("key_hash = ?", utils.HashToken(rawKey)).First(&key).Error; err != nil {
+		return nil, ErrInvalidAPIKey
+	}
+
+	if !key.IsActive() {
+		return nil, ErrInvalidAPIKey
+	}
+
+	return &key, nil
+}
+
+// Allow counts a request against the key's per-minute limit. Requests are
+// allowed when Redis is unavailable so integrations are not cut off.
+func (a *APIKeyService) Allow(key *models.APIKey) (bool, time.Duration) {
+	window := time.Now().Truncate(time.Minute)
+	counterKey := fmt.Sprintf("apikey:rate:%s:%d", key.ID, window.Unix())
+
+	count, err := a.redis.Increment(counterKey)
+	if err != nil {
+		fmt.Printf("Failed to count API key request: %v\n", err)
+		return true, 0
+	}
+
+	if count == 1 {
+		if err := a.redis.SetExpiry(counterKey, time.Minute); err != nil {
+			fmt.Printf("Failed to set API key rate limit expiry: %v\n", err)
+		}
+	}
+
+	if count > int64(key.RateLimit) {
+		return false, time.Until(window.Add(time.Minute))
+	}
+
+	return true, 0
+}
+
+// Touch records use of the key, at most once per apiKeyTouchInterval
+func (a *APIKeyService) Touch(key *models.APIKey, ipAddress string) {
+	if key.LastUsedAt != nil && time.Since(*key.LastUsedAt) < apiKeyTouchInterval {
+		return
+	}
+
+	if err := config.DB.Model(key).Updates(map[string]interface{}{
+		"last_used_at": time.Now(),
+		"last_used_ip": ipAddress,
+	}).Error; err != nil {
+		fmt.Printf("Failed to update API key usage: %v\n", err)
+	}
+}
+
+// ListKeys returns every API key, newest first
+func (a *APIKeyService) ListKeys() ([]models.APIKey, error) {
+	var keys []models.APIKey
+	if err := config.DB.Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeKey permanently disables an API key
+func (a *APIKeyService) RevokeKey(id string) error {
+	result := config.DB.Model(&models.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke API key: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+	return nil
+}
@@ -0,0 +1,415 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"backend/config"
+	"backend/models"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrAttributeNotFound     = errors.New("attribute not found")
+	ErrAttributeExists       = errors.New("an attribute with this slug already applies to this category")
+	ErrInvalidAttribute      = errors.New("invalid attribute definition")
+	ErrUnknownAttribute      = errors.New("attribute is not defined for this product's category")
+	ErrInvalidAttributeValue = errors.New("invalid attribute value")
+)
+
+// attributeSlugPattern keeps slugs usable as attr.<slug> query parameters
+var attributeSlugPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,49}$`)
+
+type AttributeService struct {
+	categories *CategoryService
+}
+
+// ProductAttribute is an attribute value as shown on a product page
+type ProductAttribute struct {
+	Name  string               `json:"name"`
+	Slug  string               `json:"slug"`
+	Type  models.AttributeType `json:"type"`
+	Unit  string               `json:"unit,omitempty"`
+	Value interface{}          `json:"value"`
+}
+
+// AttributeFilter narrows a search by one attribute: Values for text, enum
+// and boolean attributes, Min and Max for numbers
+type AttributeFilter struct {
+	Slug   string
+	Values []string
+	Min    *float64
+	Max    *float64
+}
+
+type FacetValue struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// Facet summarises an attribute across search results, e.g. Material: Steel (42)
+type Facet struct {
+	Name   string               `json:"name"`
+	Slug   string               `json:"slug"`
+	Type   models.AttributeType `json:"type"`
+	Unit   string               `json:"unit,omitempty"`
+	Values []FacetValue         `json:"values,omitempty"`
+	Min    *float64             `json:"min,omitempty"`
+	Max    *float64             `json:"max,omitempty"`
+	Count  int64                `json:"count"`
+}
+
+func NewAttributeService() *AttributeService {
+	return &AttributeService{
+		categories: NewCategoryService(),
+	}
+}
+
+// DefinitionsForCategory returns the attributes that apply to products in a
+// category: those defined on it and on each of its ancestors
+func (s *AttributeService) DefinitionsForCategory(categoryID uuid.UUID) ([]models.AttributeDefinition, error) {
+	breadcrumbs, err := s.categories.GetBreadcrumbs(categoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	categoryIDs := make([]uuid.UUID, 0, len(breadcrumbs))
+	for _, breadcrumb := range breadcrumbs {
+		categoryIDs = append(categoryIDs, breadcrumb.ID)
+	}
+
+	var definitions []models.AttributeDefinition
+	if err := config.DB.Where("category_id IN ?", categoryIDs).
+		Order("position ASC, name ASC").
+		Find(&definitions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch attributes: %w", err)
+	}
+
+	return definitions, nil
+}
+
+// ValidateDefinition checks a new or edited definition. Slugs must be unique
+// across the category's ancestors and subcategories, since products in a
+// subcategory inherit every attribute above them.
+func (s *AttributeService) ValidateDefinition(definition *models.AttributeDefinition) error {
+	if definition.Name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidAttribute)
+	}
+
+	if !attributeSlugPattern.MatchString(definition.Slug) {
+		return fmt.Errorf("%w: slug must be lowercase letters, digits, hyphens or underscores", ErrInvalidAttribute)
+	}
+
+	if !models.IsValidAttributeType(definition.Type) {
+		return fmt.Errorf("%w: type must be text, number, boolean or enum", ErrInvalidAttribute)
+	}
+
+	if definition.Type == models.AttributeTypeEnum && len(definition.Options) == 0 {
+		return fmt.Errorf("%w: enum attributes need at least one option", ErrInvalidAttribute)
+	}
+
+	ancestors, err := s.categories.GetAncestorIDs([]uuid.UUID{definition.CategoryID})
+	if err != nil {
+		return err
+	}
+	descendants, err := s.categories.GetDescendantIDs(definition.CategoryID)
+	if err != nil {
+		return err
+	}
+
+	query := config.DB.Model(&models.AttributeDefinition{}).
+		Where("slug = ? AND category_id IN ?", definition.Slug, append(ancestors, descendants...))
+	if definition.ID != uuid.Nil {
+		query = query.Where("id != ?", definition.ID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check attribute slug: %w", err)
+	}
+	if count > 0 {
+		return ErrAttributeExists
+	}
+
+	return nil
+}
+
+// DeleteDefinition removes an attribute and every product value for it
+func (s *AttributeService) DeleteDefinition(id string) error {
+	var definition models.AttributeDefinition
+	if err := config.DB.First(&definition, "id = ?", id).Error; err != nil {
+		return ErrAttributeNotFound
+	}
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("attribute_id = ?", definition.ID).Delete(&models.ProductAttributeValue{}).Error; err != nil {
+			return fmt.Errorf("failed to delete attribute values: %w", err)
+		}
+
+		if err := tx.Delete(&definition).Error; err != nil {
+			return fmt.Errorf("failed to delete attribute: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// GetProductAttributes returns the product's attribute values in display order
+func (s *AttributeService) GetProductAttributes(productID uuid.UUID) ([]ProductAttribute, error) {
+	var values []models.ProductAttributeValue
+	if err := config.DB.Preload("Attribute").
+		Joins("JOIN attribute_definitions ON attribute_definitions.id = product_attribute_values.attribute_id").
+		Where("product_attribute_values.product_id = ?", productID).
+		Order("attribute_definitions.position ASC, attribute_definitions.name ASC").
+		Find(&values).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch product attributes: %w", err)
+	}
+
+	attributes := make([]ProductAttribute, 0, len(values))
+	for _, value := range values {
+		if value.Attribute == nil {
+			continue
+		}
+
+		attribute := ProductAttribute{
+			Name: value.Attribute.Name,
+			Slug: value.Attribute.Slug,
+			Type: value.Attribute.Type,
+			Unit: value.Attribute.Unit,
+		}
+
+		switch value.Attribute.Type {
+		case models.AttributeTypeNumber:
+			attribute.Value = value.NumberValue
+		case models.AttributeTypeBoolean:
+			attribute.Value = value.BoolValue
+		default:
+			attribute.Value = value.TextValue
+		}
+
+		attributes = append(attributes, attribute)
+	}
+
+	return attributes, nil
+}
+
+// SetProductValues sets attribute values on a product, keyed by attribute
+// slug. A null value removes the attribute from the product.
+func (s *AttributeService) SetProductValues(product *models.Product, values map[string]interface{}) error {
+	definitions, err := s.DefinitionsForCategory(product.CategoryID)
+	if err != nil {
+		return err
+	}
+
+	bySlug := make(map[string]models.AttributeDefinition, len(definitions))
+	for _, definition := range definitions {
+		bySlug[definition.Slug] = definition
+	}
+
+	records := make([]models.ProductAttributeValue, 0, len(values))
+	var removed []uuid.UUID
+	for slug, raw := range values {
+		definition, ok := bySlug[slug]
+		if !ok {
+			return fmt.Errorf("%w: %s", ErrUnknownAttribute, slug)
+		}
+
+		if raw == nil {
+			removed = append(removed, definition.ID)
+			continue
+		}
+
+		record, err := typedAttributeValue(definition, raw)
+		if err != nil {
+			return err
+		}
+		record.ProductID = product.ID
+		records = append(records, record)
+	}
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		attributeIDs := removed
+		for _, record := range records {
+			attributeIDs = append(attributeIDs, record.AttributeID)
+		}
+
+		if len(attributeIDs) > 0 {
+			if err := tx.Where("product_id = ? AND attribute_id IN ?", product.ID, attributeIDs).
+				Delete(&models.ProductAttributeValue{}).Error; err != nil {
+				return fmt.Errorf("failed to replace attribute values: %w", err)
+			}
+		}
+
+		for i := range records {
+			if err := tx.Create(&records[i]).Error; err != nil {
+				return fmt.Errorf("failed to save attribute value: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// ApplyFilters restricts a product query to products matching every filter
+// except the one for excludeSlug, which lets a facet count its own options
+func (s *AttributeService) ApplyFilters(db *gorm.DB, filters []AttributeFilter, excludeSlug string) *gorm.DB {
+	for _, filter := range filters {
+		if filter.Slug == excludeSlug {
+			continue
+		}
+
+		matches := config.DB.Model(&models.ProductAttributeValue{}).
+			Select("product_attribute_values.product_id").
+			Joins("JOIN attribute_definitions ON attribute_definitions.id = product_attribute_values.attribute_id").
+			Where("attribute_definitions.slug = ?", filter.Slug)
+
+		if filter.Min != nil || filter.Max != nil {
+			if filter.Min != nil {
+				matches = matches.Where("product_attribute_values.number_value >= ?", *filter.Min)
+			}
+			if filter.Max != nil {
+				matches = matches.Where("product_attribute_values.number_value <= ?", *filter.Max)
+			}
+		} else {
+			matches = matches.Where(
+				"product_attribute_values.text_value IN ? OR CAST(product_attribute_values.bool_value AS TEXT) IN ?",
+				filter.Values, filter.Values,
+			)
+		}
+
+		db = db.Where("products.id IN (?)", matches)
+	}
+
+	return db
+}
+
+// Facets counts attribute values across the products returned by
+// productIDs. productIDs is called per attribute with that attribute's slug
+// so the attribute's own filter does not hide its other options.
+func (s *AttributeService) Facets(categoryIDs []uuid.UUID, productIDs func(excludeSlug string) *gorm.DB) ([]Facet, error) {
+	query := config.DB.Where("is_filterable = ?", true).Order("position ASC, name ASC")
+	if categoryIDs != nil {
+		query = query.Where("category_id IN ?", categoryIDs)
+	}
+
+	var definitions []models.AttributeDefinition
+	if err := query.Find(&definitions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch attributes: %w", err)
+	}
+
+	// The same slug may be defined on several categories; facet them together
+	var slugs []string
+	bySlug := make(map[string][]models.AttributeDefinition)
+	for _, definition := range definitions {
+		if _, seen := bySlug[definition.Slug]; !seen {
+			slugs = append(slugs, definition.Slug)
+		}
+		bySlug[definition.Slug] = append(bySlug[definition.Slug], definition)
+	}
+
+	facets := make([]Facet, 0, len(slugs))
+	for _, slug := range slugs {
+		group := bySlug[slug]
+		attributeIDs := make([]uuid.UUID, 0, len(group))
+		for _, definition := range group {
+			attributeIDs = append(attributeIDs, definition.ID)
+		}
+
+		facet := Facet{Name: group[0].Name, Slug: slug, Type: group[0].Type, Unit: group[0].Unit}
+		values := config.DB.Model(&models.ProductAttributeValue{}).
+			Where("attribute_id IN ? AND product_id IN (?)", attributeIDs, productIDs(slug))
+
+		switch facet.Type {
+		case models.AttributeTypeNumber:
+			var summary struct {
+				Min   *float64
+				Max   *float64
+				Count int64
+			}
+			if err := values.Select("MIN(number_value) AS min, MAX(number_value) AS max, COUNT(DISTINCT product_id) AS count").
+				Scan(&summary).Error; err != nil {
+				return nil, fmt.Errorf("failed to count %s facet: %w", slug, err)
+			}
+			facet.Min, facet.Max, facet.Count = summary.Min, summary.Max, summary.Count
+		default:
+			column := "text_value"
+			if facet.Type == models.AttributeTypeBoolean {
+				column = "CAST(bool_value AS TEXT)"
+			}
+			if err := values.Select(column + " AS value, COUNT(DISTINCT product_id) AS count").
+				Where(column + " IS NOT NULL").
+				Group(column).
+				Scan(&facet.Values).Error; err != nil {
+				return nil, fmt.Errorf("failed to count %s facet: %w", slug, err)
+			}
+			sort.SliceStable(facet.Values, func(i, j int) bool {
+				if facet.Values[i].Count != facet.Values[j].Count {
+					return facet.Values[i].Count > facet.Values[j].Count
+				}
+				return facet.Values[i].Value < facet.Values[j].Value
+			})
+			for _, value := range facet.Values {
+				facet.Count += value.Count
+			}
+		}
+
+		if facet.Count > 0 {
+			facets = append(facets, facet)
+		}
+	}
+
+	return facets, nil
+}
+
+// typedAttributeValue converts a JSON value to a record in the column for the attribute's type
+func typedAttributeValue(definition models.AttributeDefinition, raw interface{}) (models.ProductAttributeValue, error) {
+	record := models.ProductAttributeValue{AttributeID: definition.ID}
+	invalid := fmt.Errorf("%w: %s must be a %s", ErrInvalidAttributeValue, definition.Slug, definition.Type)
+
+	switch definition.Type {
+	case models.AttributeTypeNumber:
+		switch v := raw.(type) {
+		case float64:
+			record.NumberValue = &v
+		case string:
+			n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+			if err != nil {
+				return record, invalid
+			}
+			record.NumberValue = &n
+		default:
+			return record, invalid
+		}
+	case models.AttributeTypeBoolean:
+		switch v := raw.(type) {
+		case bool:
+			record.BoolValue = &v
+		case string:
+			b, err := strconv.ParseBool(v)
+			if err != nil {
+				return record, invalid
+			}
+			record.BoolValue = &b
+		default:
+			return record, invalid
+		}
+	default:
+		v, ok := raw.(string)
+		v = strings.TrimSpace(v)
+		if !ok || v == "" {
+			return record, invalid
+		}
+		if definition.Type == models.AttributeTypeEnum && !definition.Options.Allows(v) {
+			return record, fmt.Errorf("%w: %s must be one of %s", ErrInvalidAttributeValue, definition.Slug, strings.Join(definition.Options, ", "))
+		}
+		record.TextValue = &v
+	}
+
+	return record, nil
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"backend/config"
+	"backend/models"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+)
+
+// BrandService looks up brands and counts the products they make
+type BrandService struct{}
+
+// BrandListing is a brand with the number of active products it makes
+type BrandListing struct {
+	models.Brand
+	ProductCount int64 `json:"product_count"`
+}
+
+// BrandFacet counts the search results made by one brand
+type BrandFacet struct {
+	ID      uuid.UUID `json:"id"`
+	Name    string    `json:"name"`
+	Slug    string    `json:"slug"`
+	LogoURL string    `json:"logo_url,omitempty"`
+	Count   int64     `json:"count"`
+}
+
+func NewBrandService() *BrandService {
+	return &BrandService{}
+}
+
+// List returns brands ordered by name with their active product counts,
+// leaving out inactive brands unless includeInactive is set
+func (s *BrandService) List(includeInactive bool) ([]BrandListing, error) {
+	query := config.DB.Model(&models.Brand{}).
+		Select("brands.*, COUNT(products.id) AS product_count").
+		Joins("LEFT JOIN products ON products.brand_id = brands.id AND products.is_active = ?", true).
+		Group("brands.id").
+		Order("brands.name ASC")
+	if !includeInactive {
+		query = query.Where("brands.is_active = ?", true)
+	}
+
+	var brands []BrandListing
+	if err := query.Scan(&brands).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch brands: %w", err)
+	}
+
+	if brands == nil {
+		brands = []BrandListing{}
+	}
+	return brands, nil
+}
+
+// GetBySlug returns an active brand
+func (s *BrandService) GetBySlug(slug string) (*models.Brand, error) {
+	var brand models.Brand
+	if err := config.DB.Where("slug = ? AND is_active = ?", slug, true).First(&brand).Error; err != nil {
+		return nil, err
+	}
+
+	return &brand, nil
+}
+
+// ParseFilter resolves a comma separated list of brand slugs, e.g.
+// brand=dangote,lafarge, to the IDs of the active brands among them. It
+// returns nil when no brands are given and an empty slice when none match.
+func (s *BrandService) ParseFilter(value string) ([]uuid.UUID, error) {
+	var slugs []string
+	for _, slug := range strings.Split(value, ",") {
+		if slug = strings.TrimSpace(slug); slug != "" {
+			slugs = append(slugs, slug)
+		}
+	}
+	if len(slugs) == 0 {
+		return nil, nil
+	}
+
+	ids := []uuid.UUID{}
+	if err := config.DB.Model(&models.Brand{}).
+		Where("slug IN ? AND is_active = ?", slugs, true).
+		Pluck("id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch brands: %w", err)
+	}
+
+	return ids, nil
+}
+
+// ApplyFilter limits a product query to the given brands; nil brandIDs leaves
+// the query unfiltered
+func (s *BrandService) ApplyFilter(query *gorm.DB, brandIDs []uuid.UUID) *gorm.DB {
+	if brandIDs == nil {
+		return query
+	}
+	if len(brandIDs) == 0 {
+		return query.Where("1 = 0")
+	}
+
+	return query.Where("products.brand_id IN ?", brandIDs)
+}
+
+// Facet counts the products selected by productIDs per active brand, most
+// products first
+func (s *BrandService) Facet(productIDs *gorm.DB) ([]BrandFacet, error) {
+	facets := []BrandFacet{}
+	if err := config.DB.Model(&models.Brand{}).
+		Select("brands.id, brands.name, brands.slug, brands.logo_url, COUNT(products.id) AS count").
+		Joins("JOIN products ON products.brand_id = brands.id").
+		Where("brands.is_active = ? AND products.id IN (?)", true, productIDs).
+		Group("brands.id").
+		Order("count DESC, brands.name ASC").
+		Scan(&facets).Error; err != nil {
+		return nil, fmt.Errorf("failed to count brand facet: %w", err)
+	}
+
+	return facets, nil
+}
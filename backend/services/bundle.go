@@ -0,0 +1,177 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"backend/config"
+	"backend/models"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidBundle = errors.New("invalid bundle")
+	ErrBundleStock   = errors.New("bundle stock is derived from its components and cannot be set")
+	ErrInBundle      = errors.New("product is a component of a bundle")
+)
+
+// BundleService manages the components bundles are made of
+type BundleService struct{}
+
+// BundleItemInput is a component as sent by an admin
+type BundleItemInput struct {
+	ProductID uuid.UUID  `json:"product_id"`
+	VariantID *uuid.UUID `json:"variant_id"`
+	Quantity  float64    `json:"quantity"`
+}
+
+func NewBundleService() *BundleService {
+	return &BundleService{}
+}
+
+// GetComponents returns a bundle's components with their products and variants
+func (s *BundleService) GetComponents(bundleID uuid.UUID) ([]models.BundleComponent, error) {
+	var components []models.BundleComponent
+	if err := config.DB.Preload("Component").Preload("Variant").
+		Where("bundle_id = ?", bundleID).
+		Order("created_at ASC").
+		Find(&components).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch bundle components: %w", err)
+	}
+
+	return components, nil
+}
+
+// SetComponents replaces a product's components. A product with components
+// is a bundle whose stock is derived from them; an empty list turns it back
+// into an ordinary product with no stock.
+func (s *BundleService) SetComponents(bundle *models.Product, items []BundleItemInput) error {
+	if len(items) > 0 {
+		if err := s.validate(bundle, items); err != nil {
+			return err
+		}
+	}
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("bundle_id = ?", bundle.ID).Delete(&models.BundleComponent{}).Error; err != nil {
+			return fmt.Errorf("failed to clear bundle components: %w", err)
+		}
+
+		components := make([]models.BundleComponent, 0, len(items))
+		for _, item := range items {
+			components = append(components, models.BundleComponent{
+				BundleID:           bundle.ID,
+				ComponentProductID: item.ProductID,
+				ComponentVariantID: item.VariantID,
+				Quantity:           models.RoundQuantity(item.Quantity),
+			})
+		}
+
+		if len(components) > 0 {
+			if err := tx.Create(&components).Error; err != nil {
+				return fmt.Errorf("failed to save bundle components: %w", err)
+			}
+		}
+
+		stock := gorm.Expr("0")
+		if len(components) > 0 {
+			stock = gorm.Expr("bundle_stock(?)", bundle.ID)
+		}
+
+		if err := tx.Model(bundle).Updates(map[string]interface{}{
+			"is_bundle":      len(components) > 0,
+			"stock_quantity": stock,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update bundle: %w", err)
+		}
+
+		return tx.First(bundle, "id = ?", bundle.ID).Error
+	})
+}
+
+// IsComponent reports whether a product, or one of its variants when
+// variantID is set, is part of any bundle
+func (s *BundleService) IsComponent(productID string, variantID *string) (bool, error) {
+	query := config.DB.Model(&models.BundleComponent{})
+	if variantID != nil {
+		query = query.Where("component_variant_id = ?", *variantID)
+	} else {
+		query = query.Where("component_product_id = ?", productID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check bundles: %w", err)
+	}
+
+	return count > 0, nil
+}
+
+func (s *BundleService) validate(bundle *models.Product, items []BundleItemInput) error {
+	if !bundle.IsCountable() {
+		return fmt.Errorf("%w: bundles must be sold by the piece or box", ErrInvalidBundle)
+	}
+
+	var variantCount int64
+	if err := config.DB.Model(&models.ProductVariant{}).Where("product_id = ?", bundle.ID).Count(&variantCount).Error; err != nil {
+		return fmt.Errorf("failed to check variants: %w", err)
+	}
+	if variantCount > 0 {
+		return fmt.Errorf("%w: products with variants cannot be bundles", ErrInvalidBundle)
+	}
+
+	var usedAsComponent int64
+	if err := config.DB.Model(&models.BundleComponent{}).Where("component_product_id = ?", bundle.ID).Count(&usedAsComponent).Error; err != nil {
+		return fmt.Errorf("failed to check bundles: %w", err)
+	}
+	if usedAsComponent > 0 {
+		return fmt.Errorf("%w: a component of another bundle cannot be a bundle", ErrInvalidBundle)
+	}
+
+	seen := map[string]bool{}
+	for _, item := range items {
+		key := item.ProductID.String()
+		if item.VariantID != nil {
+			key += "/" + item.VariantID.String()
+		}
+		if seen[key] {
+			return fmt.Errorf("%w: component %s is listed more than once", ErrInvalidBundle, key)
+		}
+		seen[key] = true
+
+		if item.ProductID == bundle.ID {
+			return fmt.Errorf("%w: a bundle cannot contain itself", ErrInvalidBundle)
+		}
+
+		var component models.Product
+		if err := config.DB.First(&component, "id = ?", item.ProductID).Error; err != nil {
+			return fmt.Errorf("%w: product %s not found", ErrInvalidBundle, item.ProductID)
+		}
+		if component.IsBundle {
+			return fmt.Errorf("%w: %s is itself a bundle", ErrInvalidBundle, component.Name)
+		}
+
+		var variantCount int64
+		if err := config.DB.Model(&models.ProductVariant{}).Where("product_id = ?", component.ID).Count(&variantCount).Error; err != nil {
+			return fmt.Errorf("failed to check variants: %w", err)
+		}
+
+		switch {
+		case item.VariantID == nil && variantCount > 0:
+			return fmt.Errorf("%w: %s has variants; variant_id is required", ErrInvalidBundle, component.Name)
+		case item.VariantID != nil:
+			var variant models.ProductVariant
+			if err := config.DB.First(&variant, "id = ? AND product_id = ?", *item.VariantID, component.ID).Error; err != nil {
+				return fmt.Errorf("%w: variant %s not found for %s", ErrInvalidBundle, *item.VariantID, component.Name)
+			}
+		}
+
+		if err := component.ValidateQuantity(item.Quantity); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrInvalidBundle, component.Name, err)
+		}
+	}
+
+	return nil
+}
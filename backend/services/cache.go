@@ -3,8 +3,10 @@ package services
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"github.com/redis/go-redis/v9"
@@ -15,10 +17,28 @@ type RedisService struct {
 	ctx    context.Context
 }
 
+// ErrCacheMiss is returned by Get when the key does not exist, as opposed to
+// Redis being unreachable
+var ErrCacheMiss = errors.New("key not found")
+
 type CacheOptions struct {
 	TTL time.Duration
 }
 
+var (
+	sharedRedis     *RedisService
+	sharedRedisOnce sync.Once
+)
+
+// SharedRedisService returns the process-wide Redis client, connecting on
+// first use. Request paths use it rather than opening a client per call.
+func SharedRedisService() *RedisService {
+	sharedRedisOnce.Do(func() {
+		sharedRedis = NewRedisService()
+	})
+	return sharedRedis
+}
+
 func NewRedisService() *RedisService {
 	rdb := redis.NewClient(&redis.Options{
 		Addr:     os.Getenv("REDIS_ADDR"),
@@ -68,7 +88,7 @@ func (r *RedisService) Get(key string, dest interface{}) error {
 	val, err := r.client.Get(r.ctx, key).Result()
 	if err != nil {
 		if err == redis.Nil {
-			return fmt.Errorf("key not found: %s", key)
+			return fmt.Errorf("%w: %s", ErrCacheMiss, key)
 		}
 		return fmt.Errorf("failed to get key: %w", err)
 	}
@@ -90,6 +110,17 @@ func (r *RedisService) Exists(key string) (bool, error) {
 	return result > 0, nil
 }
 
+// SetIfAbsent stores a value only if the key does not exist yet, reporting
+// whether it did. Replicas use it to claim work only one of them should do.
+func (r *RedisService) SetIfAbsent(key string, value interface{}, ttl time.Duration) (bool, error) {
+	jsonValue, err := json.Marshal(value)
+	if err != nil {
+		return false, fmt.Errorf("failed to marshal value: %w", err)
+	}
+
+	return r.client.SetNX(r.ctx, key, jsonValue, ttl).Result()
+}
+
 // SetExpiry sets expiry for an existing key
 func (r *RedisService) SetExpiry(key string, ttl time.Duration) error {
 	return r.client.Expire(r.ctx, key, ttl).Err()
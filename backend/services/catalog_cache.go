@@ -0,0 +1,133 @@
+package services
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"time"
+
+	"backend/config"
+)
+
+// catalogCacheGenerationKey is bumped on every catalog change. Cache keys
+// embed the generation, so bumping it retires every cached response at once
+// and the old entries simply expire.
+const catalogCacheGenerationKey = "catalog:cache:generation"
+
+// CatalogCacheService caches public catalog responses in Redis
+type CatalogCacheService struct {
+	redis *RedisService
+	ttl   time.Duration
+}
+
+// CachedResponse is a response body with the ETag that identifies it
+type CachedResponse struct {
+	ETag string          `json:"etag"`
+	Body json.RawMessage `json:"body"`
+}
+
+func NewCatalogCacheService() *CatalogCacheService {
+	ttl, err := time.ParseDuration(os.Getenv("CATALOG_CACHE_TTL"))
+	if err != nil || ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+
+	return &CatalogCacheService{
+		redis: SharedRedisService(),
+		ttl:   ttl,
+	}
+}
+
+// Key returns the cache key for a path and its query parameters. Parameters
+// are escaped and sorted, and empty ones dropped, so ?page=1&sort=name and
+// ?sort=name&page=1&q= share an entry while a value containing "&" cannot
+// pass for two parameters.
+func (s *CatalogCacheService) Key(path string, query map[string]string) string {
+	params := url.Values{}
+	for name, value := range query {
+		if value != "" {
+			params.Set(name, value)
+		}
+	}
+
+	var generation int64
+	if err := s.redis.Get(catalogCacheGenerationKey, &generation); err != nil {
+		generation = 0
+	}
+
+	sum := sha256.Sum256([]byte(url.PathEscape(path) + "?" + params.Encode()))
+	return fmt.Sprintf("catalog:cache:%d:%s", generation, hex.EncodeToString(sum[:16]))
+}
+
+// Get returns the cached response for a key, if any
+func (s *CatalogCacheService) Get(key string) (*CachedResponse, bool) {
+	var cached CachedResponse
+	if err := s.redis.Get(key, &cached); err != nil {
+		return nil, false
+	}
+
+	return &cached, true
+}
+
+// Set caches a JSON response body under a key and returns it with its ETag
+func (s *CatalogCacheService) Set(key string, body []byte) *CachedResponse {
+	cached := &CachedResponse{ETag: ETag(body), Body: append(json.RawMessage(nil), body...)}
+
+	// A TTL under a second would round to no expiry in Redis
+	ttl := s.entryTTL()
+	if ttl < time.Second {
+		return cached
+	}
+
+	if err := s.redis.Set(key, cached, CacheOptions{TTL: ttl}); err != nil {
+		fmt.Printf("Failed to cache catalog response: %v\n", err)
+	}
+
+	return cached
+}
+
+// entryTTL caps the TTL at the next scheduled sale start or end. Current
+// prices are worked out when a response is built, so no cached response may
+// outlive a change of price.
+func (s *CatalogCacheService) entryTTL() time.Duration {
+	now := time.Now()
+
+	var next sql.NullTime
+	if err := config.DB.Raw(`
+		SELECT MIN(boundary) FROM (
+			SELECT sale_starts_at AS boundary FROM products WHERE sale_price IS NOT NULL
+			UNION ALL
+			SELECT sale_ends_at FROM products WHERE sale_price IS NOT NULL
+			UNION ALL
+			SELECT sale_starts_at FROM product_variants WHERE sale_price IS NOT NULL
+			UNION ALL
+			SELECT sale_ends_at FROM product_variants WHERE sale_price IS NOT NULL
+		) boundaries
+		WHERE boundary > ?`, now,
+	).Row().Scan(&next); err != nil {
+		fmt.Printf("Failed to find the next sale boundary: %v\n", err)
+		return 0
+	}
+
+	if next.Valid && next.Time.Sub(now) < s.ttl {
+		return next.Time.Sub(now)
+	}
+	return s.ttl
+}
+
+// Invalidate retires every cached catalog response
+func (s *CatalogCacheService) Invalidate() {
+	if _, err := s.redis.Increment(catalogCacheGenerationKey); err != nil {
+		fmt.Printf("Failed to invalidate catalog cache: %v\n", err)
+	}
+}
+
+// ETag returns a strong entity tag for a response body
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
@@ -0,0 +1,138 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"backend/config"
+	"backend/models"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+var ErrCategoryCycle = errors.New("a category cannot be moved under itself or one of its subcategories")
+
+type CategoryService struct{}
+
+// CategoryBreadcrumb is one step on the path from the root to a category
+type CategoryBreadcrumb struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	Slug string    `json:"slug"`
+}
+
+func NewCategoryService() *CategoryService {
+	return &CategoryService{}
+}
+
+// GetTree returns root categories with their subcategories nested under
+// Children, each level ordered by position then name
+func (s *CategoryService) GetTree() ([]models.Category, error) {
+	var categories []models.Category
+	if err := config.DB.Order("position ASC, name ASC").Find(&categories).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch categories: %w", err)
+	}
+
+	byParent := make(map[uuid.UUID][]models.Category)
+	var roots []models.Category
+	for _, category := range categories {
+		if category.ParentID == nil {
+			roots = append(roots, category)
+		} else {
+			byParent[*category.ParentID] = append(byParent[*category.ParentID], category)
+		}
+	}
+
+	var attach func(nodes []models.Category) []models.Category
+	attach = func(nodes []models.Category) []models.Category {
+		for i := range nodes {
+			nodes[i].Children = attach(byParent[nodes[i].ID])
+		}
+		return nodes
+	}
+
+	if roots == nil {
+		roots = []models.Category{}
+	}
+	return attach(roots), nil
+}
+
+// GetDescendantIDs returns the category and every category below it
+func (s *CategoryService) GetDescendantIDs(categoryID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if err := config.DB.Raw(`
+		WITH RECURSIVE subtree AS (
+			SELECT id FROM categories WHERE id = ?
+			UNION
+			SELECT c.id FROM categories c JOIN subtree s ON c.parent_id = s.id
+		)
+		SELECT id FROM subtree`, categoryID).Scan(&ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch subcategories: %w", err)
+	}
+
+	return ids, nil
+}
+
+// GetDescendantIDsBySlug resolves a category slug to the IDs of its subtree.
+// An unknown slug yields no IDs.
+func (s *CategoryService) GetDescendantIDsBySlug(slug string) ([]uuid.UUID, error) {
+	var category models.Category
+	if err := config.DB.Where("slug = ?", slug).First(&category).Error; err != nil {
+		return []uuid.UUID{}, nil
+	}
+
+	return s.GetDescendantIDs(category.ID)
+}
+
+// GetBreadcrumbs returns the path from the root category down to the given one
+func (s *CategoryService) GetBreadcrumbs(categoryID uuid.UUID) ([]CategoryBreadcrumb, error) {
+	var breadcrumbs []CategoryBreadcrumb
+	if err := config.DB.Raw(`
+		WITH RECURSIVE ancestors AS (
+			SELECT id, name, slug, parent_id, 0 AS depth FROM categories WHERE id = ?
+			UNION
+			SELECT c.id, c.name, c.slug, c.parent_id, a.depth + 1
+			FROM categories c JOIN ancestors a ON c.id = a.parent_id
+		)
+		SELECT id, name, slug FROM ancestors ORDER BY depth DESC`, categoryID).Scan(&breadcrumbs).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch breadcrumbs: %w", err)
+	}
+
+	return breadcrumbs, nil
+}
+
+// GetAncestorIDs returns the given categories together with every category above them
+func (s *CategoryService) GetAncestorIDs(categoryIDs []uuid.UUID) ([]uuid.UUID, error) {
+	ids := []uuid.UUID{}
+	if len(categoryIDs) == 0 {
+		return ids, nil
+	}
+
+	if err := config.DB.Raw(`
+		WITH RECURSIVE ancestors AS (
+			SELECT id, parent_id FROM categories WHERE id IN ?
+			UNION
+			SELECT c.id, c.parent_id FROM categories c JOIN ancestors a ON c.id = a.parent_id
+		)
+		SELECT id FROM ancestors`, categoryIDs).Scan(&ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch parent categories: %w", err)
+	}
+
+	return ids, nil
+}
+
+// ValidateParent checks that moving a category under parentID keeps the tree acyclic
+func (s *CategoryService) ValidateParent(categoryID, parentID uuid.UUID) error {
+	descendants, err := s.GetDescendantIDs(categoryID)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range descendants {
+		if id == parentID {
+			return ErrCategoryCycle
+		}
+	}
+
+	return nil
+}
@@ -138,7 +138,7 @@ func (s *SendGridService) SendOrderConfirmation(order *models.Order, user *model
 		</body>
 		</html>
 	`, user.FullName, order.ID, order.Total, order.PlacedAt.Format("January 2, 2006"),
-		order.AddressJSON.Line, order.AddressJSON.City, order.AddressJSON.Country)
+		order.AddressJSON.Label, order.AddressJSON.Line, order.AddressJSON.City, order.AddressJSON.Country)
 
 	return s.SendEmail(user.Email, user.FullName, subject, htmlContent)
 }
@@ -204,6 +204,66 @@ func (s *SendGridService) SendPasswordReset(user *models.User, resetToken string
 	return s.SendEmail(user.Email, user.FullName, subject, htmlContent)
 }
 
+// SendEmailVerification sends the email address verification link
+func (s *SendGridService) SendEmailVerification(user *models.User, verificationToken string) error {
+	subject := "Verify Your Email - Hardware Store"
+
+	verifyURL := fmt.Sprintf("%s/verify-email?token=%s", os.Getenv("FRONTEND_URL"), verificationToken)
+
+	htmlContent := fmt.Sprintf(`
+		<!DOCTYPE html>
+		<html>
+		<head>
+			<title>Verify Your Email</title>
+		</head>
+		<body>
+			<h2>Verify Your Email Address</h2>
+			<p>Dear %s,</p>
+			<p>Please confirm that this is your email address.</p>
+			
+			<p><a href="%s">Verify Email</a></p>
+			
+			<p>If you didn't create an account, please ignore this email.</p>
+			<p>This link will expire in 24 hours.</p>
+			
+			<p>Best regards,<br>Hardware Store Team</p>
+		</body>
+		</html>
+	`, user.FullName, verifyURL)
+
+	return s.SendEmail(user.Email, user.FullName, subject, htmlContent)
+}
+
+// SendAccountLocked tells a user their account was locked and how to unlock it
+func (s *SendGridService) SendAccountLocked(user *models.User, unlockToken string, lockedUntil time.Time) error {
+	subject := "Account Locked - Hardware Store"
+
+	unlockURL := fmt.Sprintf("%s/unlock-account?token=%s", os.Getenv("FRONTEND_URL"), unlockToken)
+
+	htmlContent := fmt.Sprintf(`
+		<!DOCTYPE html>
+		<html>
+		<head>
+			<title>Account Locked</title>
+		</head>
+		<body>
+			<h2>Your Account Has Been Locked</h2>
+			<p>Dear %s,</p>
+			<p>We locked your account after several failed sign-in attempts.</p>
+			
+			<p>It will unlock automatically at %s, or you can unlock it now:</p>
+			<p><a href="%s">Unlock Account</a></p>
+			
+			<p>If these attempts weren't you, we recommend resetting your password.</p>
+			
+			<p>Best regards,<br>Hardware Store Team</p>
+		</body>
+		</html>
+	`, user.FullName, lockedUntil.Format("January 2, 2006 15:04 MST"), unlockURL)
+
+	return s.SendEmail(user.Email, user.FullName, subject, htmlContent)
+}
+
 // SendWelcomeEmail sends welcome email to new users
 func (s *SendGridService) SendWelcomeEmail(user *models.User) error {
 	subject := "Welcome to Hardware Store!"
@@ -254,7 +314,7 @@ func (s *SendGridService) SendLowStockAlert(product *models.Product, adminEmail
 			<h3>Product Details:</h3>
 			<p><strong>Name:</strong> %s</p>
 			<p><strong>SKU:</strong> %s</p>
-			<p><strong>Current Stock:</strong> %d</p>
+			<p><strong>Current Stock:</strong> %g %s</p>
 			<p><strong>Category:</strong> %s</p>
 			
 			<p>Please consider restocking this item.</p>
@@ -262,7 +322,7 @@ func (s *SendGridService) SendLowStockAlert(product *models.Product, adminEmail
 			<p>Best regards,<br>Hardware Store System</p>
 		</body>
 		</html>
-	`, product.Name, product.SKU, product.StockQuantity, "Tools") // You might want to get category name
+	`, product.Name, product.SKU, product.StockQuantity, product.Unit, "Tools") // You might want to get category name
 
 	return s.SendEmail(adminEmail, "Admin", subject, htmlContent)
 }
@@ -0,0 +1,164 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"backend/config"
+	"backend/models"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrProductNotFound   = errors.New("product not found")
+	ErrVariantNotFound   = errors.New("variant not found")
+	ErrVariantRequired   = errors.New("this product has variants; variant_id is required")
+	ErrInsufficientStock = errors.New("insufficient stock")
+)
+
+// InventoryService resolves what a shopper is buying, a product or one of its
+// variants, and keeps stock levels for either in step
+type InventoryService struct{}
+
+// StockItem is the product, and variant if any, a cart or order line refers to
+type StockItem struct {
+	Product *models.Product
+	Variant *models.ProductVariant
+}
+
+func NewInventoryService() *InventoryService {
+	return &InventoryService{}
+}
+
+// Resolve loads an active product and, for products with active variants, the
+// requested variant
+func (s *InventoryService) Resolve(productID string, variantID *string) (*StockItem, error) {
+	var product models.Product
+	if err := config.DB.Where("id = ? AND is_active = ?", productID, true).First(&product).Error; err != nil {
+		return nil, ErrProductNotFound
+	}
+
+	if variantID == nil || *variantID == "" {
+		var variantCount int64
+		if err := config.DB.Model(&models.ProductVariant{}).
+			Where("product_id = ? AND is_active = ?", product.ID, true).
+			Count(&variantCount).Error; err != nil {
+			return nil, fmt.Errorf("failed to check variants: %w", err)
+		}
+		if variantCount > 0 {
+			return nil, ErrVariantRequired
+		}
+		return &StockItem{Product: &product}, nil
+	}
+
+	var variant models.ProductVariant
+	if err := config.DB.Where("id = ? AND product_id = ? AND is_active = ?", *variantID, product.ID, true).
+		First(&variant).Error; err != nil {
+		return nil, ErrVariantNotFound
+	}
+
+	return &StockItem{Product: &product, Variant: &variant}, nil
+}
+
+// Stock returns the quantity available to sell
+func (i *StockItem) Stock() float64 {
+	if i.Variant != nil {
+		return i.Variant.StockQuantity
+	}
+	return i.Product.StockQuantity
+}
+
+// Price returns the current unit price, the sale price while a sale runs
+func (i *StockItem) Price() float64 {
+	if i.Variant != nil {
+		return i.Variant.PriceNow()
+	}
+	return i.Product.PriceNow()
+}
+
+// DisplayName names the item for messages, including the variant if any
+func (i *StockItem) DisplayName() string {
+	if i.Variant != nil {
+		return i.Product.Name + " (" + i.Variant.Name + ")"
+	}
+	return i.Product.Name
+}
+
+// Deduct removes stock for a sale. The check and the update happen in one
+// statement so concurrent orders cannot oversell.
+func (s *InventoryService) Deduct(tx *gorm.DB, productID uuid.UUID, variantID *uuid.UUID, quantity float64) error {
+	result := s.stockQuery(tx, productID, variantID).
+		Where("stock_quantity >= ?", quantity).
+		Update("stock_quantity", gorm.Expr("stock_quantity - ?", quantity))
+	if result.Error != nil {
+		return fmt.Errorf("failed to update stock: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrInsufficientStock
+	}
+	return nil
+}
+
+// DeductOrderItem removes the stock an order line sells. A bundle's own
+// stock is derived, so its components are deducted instead and the line
+// records what was taken for RestoreOrderItem.
+func (s *InventoryService) DeductOrderItem(tx *gorm.DB, item *models.OrderItem) error {
+	var components []models.BundleComponent
+	if err := tx.Where("bundle_id = ?", item.ProductID).Find(&components).Error; err != nil {
+		return fmt.Errorf("failed to fetch bundle components: %w", err)
+	}
+
+	if len(components) == 0 {
+		return s.Deduct(tx, item.ProductID, item.VariantID, item.Quantity)
+	}
+
+	deductions := make(models.BundleDeductions, 0, len(components))
+	for _, component := range components {
+		deduction := models.BundleDeduction{
+			ProductID: component.ComponentProductID,
+			VariantID: component.ComponentVariantID,
+			Quantity:  models.RoundQuantity(component.Quantity * item.Quantity),
+		}
+		if err := s.Deduct(tx, deduction.ProductID, deduction.VariantID, deduction.Quantity); err != nil {
+			return err
+		}
+		deductions = append(deductions, deduction)
+	}
+
+	item.BundleDeductions = deductions
+	return nil
+}
+
+// RestoreOrderItem returns the stock an order line took, to the components
+// for bundles
+func (s *InventoryService) RestoreOrderItem(tx *gorm.DB, item *models.OrderItem) error {
+	if len(item.BundleDeductions) == 0 {
+		return s.Restore(tx, item.ProductID, item.VariantID, item.Quantity)
+	}
+
+	for _, deduction := range item.BundleDeductions {
+		if err := s.Restore(tx, deduction.ProductID, deduction.VariantID, deduction.Quantity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Restore returns stock from a cancelled sale
+func (s *InventoryService) Restore(tx *gorm.DB, productID uuid.UUID, variantID *uuid.UUID, quantity float64) error {
+	if err := s.stockQuery(tx, productID, variantID).
+		Update("stock_quantity", gorm.Expr("stock_quantity + ?", quantity)).Error; err != nil {
+		return fmt.Errorf("failed to restore stock: %w", err)
+	}
+	return nil
+}
+
+// stockQuery targets the row that holds stock for a product or variant
+func (s *InventoryService) stockQuery(tx *gorm.DB, productID uuid.UUID, variantID *uuid.UUID) *gorm.DB {
+	if variantID != nil {
+		return tx.Model(&models.ProductVariant{}).Where("id = ? AND product_id = ?", *variantID, productID)
+	}
+	return tx.Model(&models.Product{}).Where("id = ?", productID)
+}
@@ -0,0 +1,227 @@
+package services
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/config"
+	"backend/models"
+	"backend/utils"
+)
+
+const (
+	// loginDelayAfter is the number of failures tolerated before delays start
+	loginDelayAfter = 3
+	// loginMaxDelay caps the progressive delay between attempts
+	loginMaxDelay = 30 * time.Second
+)
+
+// LoginProtectionService tracks failed logins per account in Redis and locks
+// accounts that keep failing, independent of the caller's IP address
+type LoginProtectionService struct {
+	redis           *RedisService
+	emailService    *SendGridService
+	maxAttempts     int64
+	attemptWindow   time.Duration
+	lockoutDuration time.Duration
+}
+
+type LoginFailureResult struct {
+	Failures    int64
+	RetryAfter  time.Duration
+	LockedUntil *time.Time
+}
+
+func NewLoginProtectionService() *LoginProtectionService {
+	maxAttempts, err := strconv.ParseInt(os.Getenv("LOGIN_MAX_ATTEMPTS"), 10, 64)
+	if err != nil || maxAttempts <= 0 {
+		maxAttempts = 5
+	}
+
+	attemptWindow, err := time.ParseDuration(os.Getenv("LOGIN_ATTEMPT_WINDOW"))
+	if err != nil {
+		attemptWindow = 15 * time.Minute
+	}
+
+	lockoutDuration, err := time.ParseDuration(os.Getenv("LOGIN_LOCKOUT_DURATION"))
+	if err != nil {
+		lockoutDuration = 15 * time.Minute
+	}
+
+	return &LoginProtectionService{
+		redis:           SharedRedisService(),
+		emailService:    NewSendGridService(),
+		maxAttempts:     maxAttempts,
+		attemptWindow:   attemptWindow,
+		lockoutDuration: lockoutDuration,
+	}
+}
+
+// RetryAfter returns how long the caller must wait before trying this email again
+func (l *LoginProtectionService) RetryAfter(email string) time.Duration {
+	ttl, err := l.redis.GetTTL(l.delayKey(email))
+	if err != nil || ttl <= 0 {
+		return 0
+	}
+	return ttl
+}
+
+// RecordFailure counts a failed login and applies a progressive delay. When
+// user is non-nil and the limit is reached the account is locked and the
+// owner is emailed an unlock link; attempts on an already locked account
+// extend the lock instead.
+func (l *LoginProtectionService) RecordFailure(email string, user *models.User) LoginFailureResult {
+	result := LoginFailureResult{}
+
+	failures, err := l.redis.Increment(l.failuresKey(email))
+	if err != nil {
+		// Without Redis we fall back to the IP-based rate limiter alone
+		fmt.Printf("Failed to record login failure: %v\n", err)
+		return result
+	}
+	result.Failures = failures
+
+	if failures == 1 {
+		if err := l.redis.SetExpiry(l.failuresKey(email), l.attemptWindow); err != nil {
+			fmt.Printf("Failed to set login failure expiry: %v\n", err)
+		}
+	}
+
+	if failures >= loginDelayAfter {
+		delay := time.Duration(math.Pow(2, float64(failures-loginDelayAfter))) * time.Second
+		if delay > loginMaxDelay {
+			delay = loginMaxDelay
+		}
+		if err := l.redis.Set(l.delayKey(email), failures, CacheOptions{TTL: delay}); err == nil {
+			result.RetryAfter = delay
+		}
+	}
+
+	if user != nil && user.IsLocked() {
+		lockedUntil, err := l.extendLock(user)
+		if err != nil {
+			fmt.Printf("Failed to extend account lock: %v\n", err)
+		} else {
+			result.LockedUntil = lockedUntil
+		}
+	} else if user != nil && failures >= l.maxAttempts {
+		lockedUntil, err := l.Lock(user)
+		if err != nil {
+			fmt.Printf("Failed to lock account: %v\n", err)
+		} else {
+			result.LockedUntil = lockedUntil
+		}
+	}
+
+	return result
+}
+
+// RecordSuccess clears the failure counter after a successful login
+func (l *LoginProtectionService) RecordSuccess(email string) {
+	l.redis.Delete(l.failuresKey(email))
+	l.redis.Delete(l.delayKey(email))
+}
+
+// Lock locks the account until the lockout duration passes and emails an unlock link
+func (l *LoginProtectionService) Lock(user *models.User) (*time.Time, error) {
+	lockedUntil := time.Now().Add(l.lockoutDuration)
+	if err := config.DB.Model(user).Update("locked_until", lockedUntil).Error; err != nil {
+		return nil, fmt.Errorf("failed to lock account: %w", err)
+	}
+
+	// Start counting afresh once the lock lifts
+	l.redis.Delete(l.failuresKey(user.Email))
+
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return &lockedUntil, err
+	}
+
+	if err := l.redis.Set(l.unlockKey(token), user.ID.String(), CacheOptions{TTL: l.lockoutDuration}); err != nil {
+		return &lockedUntil, fmt.Errorf("failed to store unlock token: %w", err)
+	}
+	// Remember the token per account so extending the lock can extend it too
+	if err := l.redis.Set(l.unlockTokenKey(user), l.unlockKey(token), CacheOptions{TTL: l.lockoutDuration}); err != nil {
+		fmt.Printf("Failed to store unlock token reference: %v\n", err)
+	}
+
+	go func() {
+		if err := l.emailService.SendAccountLocked(user, token, lockedUntil); err != nil {
+			fmt.Printf("Failed to send account locked email: %v\n", err)
+		}
+	}()
+
+	return &lockedUntil, nil
+}
+
+// extendLock pushes a locked account's lock, and the unlock link already
+// emailed for it, back to a full lockout duration from now
+func (l *LoginProtectionService) extendLock(user *models.User) (*time.Time, error) {
+	lockedUntil := time.Now().Add(l.lockoutDuration)
+	if err := config.DB.Model(user).Update("locked_until", lockedUntil).Error; err != nil {
+		return nil, fmt.Errorf("failed to extend account lock: %w", err)
+	}
+
+	var unlockKey string
+	if err := l.redis.Get(l.unlockTokenKey(user), &unlockKey); err == nil {
+		l.redis.SetExpiry(unlockKey, l.lockoutDuration)
+		l.redis.SetExpiry(l.unlockTokenKey(user), l.lockoutDuration)
+	}
+
+	return &lockedUntil, nil
+}
+
+// UnlockWithToken unlocks the account an emailed unlock token belongs to
+func (l *LoginProtectionService) UnlockWithToken(token string) (*models.User, error) {
+	var userID string
+	if err := l.redis.Get(l.unlockKey(token), &userID); err != nil {
+		return nil, fmt.Errorf("invalid or expired unlock token")
+	}
+
+	var user models.User
+	if err := config.DB.Where("id = ?", userID).First(&user).Error; err != nil {
+		return nil, fmt.Errorf("user not found: %w", err)
+	}
+
+	if err := l.Unlock(&user); err != nil {
+		return nil, err
+	}
+
+	l.redis.Delete(l.unlockKey(token))
+	return &user, nil
+}
+
+// Unlock clears the lock and failure counters for the account
+func (l *LoginProtectionService) Unlock(user *models.User) error {
+	if err := config.DB.Model(user).Update("locked_until", nil).Error; err != nil {
+		return fmt.Errorf("failed to unlock account: %w", err)
+	}
+
+	l.RecordSuccess(user.Email)
+	return nil
+}
+
+func (l *LoginProtectionService) failuresKey(email string) string {
+	return "login:failures:" + normalizeEmail(email)
+}
+
+func (l *LoginProtectionService) delayKey(email string) string {
+	return "login:delay:" + normalizeEmail(email)
+}
+
+func (l *LoginProtectionService) unlockKey(token string) string {
+	return "login:unlock:" + utils.HashToken(token)
+}
+
+func (l *LoginProtectionService) unlockTokenKey(user *models.User) string {
+	return "login:unlock-token:" + user.ID.String()
+}
+
+// normalizeEmail lowercases and trims an email so counters can't be dodged by case
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
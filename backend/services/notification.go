@@ -131,6 +131,10 @@ func (n *NotificationService) sendSMSNotification(req NotificationRequest, notif
 		return fmt.Errorf("user has no phone number")
 	}
 
+	if PhoneVerificationRequired() && !user.IsPhoneVerified() {
+		return fmt.Errorf("user phone number is not verified")
+	}
+
 	return n.smsService.SendSMS(*user.Phone, req.Message)
 }
 
@@ -262,7 +266,7 @@ func (n *NotificationService) SendLowStockAlert(product *models.Product, adminEm
 		UserID:  "", // No specific user for admin alerts
 		Channel: models.NotificationChannelEmail,
 		Subject: "Low Stock Alert - Hardware Store",
-		Message: fmt.Sprintf("LOW STOCK ALERT: %s (SKU: %s) has only %d units remaining. Please restock.", product.Name, product.SKU, product.StockQuantity),
+		Message: fmt.Sprintf("LOW STOCK ALERT: %s (SKU: %s) has only %g %s remaining. Please restock.", product.Name, product.SKU, product.StockQuantity, product.Unit),
 	}
 
 	// For admin alerts, we'll send directly to the email service
@@ -0,0 +1,181 @@
+package services
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	"backend/config"
+	"backend/models"
+	"backend/utils"
+)
+
+const (
+	otpLoginExpiry        = 5 * time.Minute
+	otpLoginMaxAttempts   = 5
+	otpLoginMaxRequests   = 5 // codes per number per otpLoginRequestWindow
+	otpLoginRequestWindow = time.Hour
+	otpLoginResendDelay   = time.Minute
+)
+
+var (
+	ErrOTPInvalidPhone    = errors.New("invalid phone number")
+	ErrOTPInvalid         = errors.New("invalid or expired code")
+	ErrOTPTooManyAttempts = errors.New("too many attempts, request a new code")
+	ErrOTPTooManyRequests = errors.New("too many codes requested for this number")
+	ErrOTPResendTooSoon   = errors.New("a code was sent recently")
+	ErrOTPUnavailable     = errors.New("sign-in codes are temporarily unavailable")
+)
+
+// OTPLoginService signs customers in with a one-time code texted to their
+// phone. Codes are kept hashed in Redis and expire after otpLoginExpiry.
+type OTPLoginService struct {
+	redis      *RedisService
+	smsService *TwilioService
+}
+
+type otpLoginCode struct {
+	UserID   string `json:"user_id"`
+	CodeHash string `json:"code_hash"`
+}
+
+func NewOTPLoginService() *OTPLoginService {
+	return &OTPLoginService{
+		redis:      SharedRedisService(),
+		smsService: NewTwilioService(),
+	}
+}
+
+// RequestCode texts a sign-in code to the number if it belongs to a customer.
+// Unknown numbers are rate limited the same way but no SMS is sent, so callers
+// cannot tell which numbers are registered.
+func (o *OTPLoginService) RequestCode(phone string) (time.Duration, error) {
+	phone = cleanPhoneNumber(phone)
+	if !o.smsService.ValidatePhoneNumber(phone) {
+		return 0, ErrOTPInvalidPhone
+	}
+
+	if ttl, err := o.redis.GetTTL(o.cooldownKey(phone)); err == nil && ttl > 0 {
+		return ttl, ErrOTPResendTooSoon
+	}
+
+	requests, err := o.redis.Increment(o.requestsKey(phone))
+	if err != nil {
+		fmt.Printf("Failed to count sign-in code request: %v\n", err)
+		return 0, ErrOTPUnavailable
+	}
+	if requests == 1 {
+		o.redis.SetExpiry(o.requestsKey(phone), otpLoginRequestWindow)
+	}
+	if requests > otpLoginMaxRequests {
+		ttl, _ := o.redis.GetTTL(o.requestsKey(phone))
+		return ttl, ErrOTPTooManyRequests
+	}
+
+	o.redis.Set(o.cooldownKey(phone), true, CacheOptions{TTL: otpLoginResendDelay})
+
+	user, err := o.findCustomer(phone)
+	if err != nil {
+		return 0, nil
+	}
+
+	code, err := utils.GenerateNumericCode(6)
+	if err != nil {
+		return 0, err
+	}
+
+	// A new code replaces the previous one and resets its attempts
+	if err := o.redis.Set(o.codeKey(phone), otpLoginCode{
+		UserID:   user.ID.String(),
+		CodeHash: utils.HashToken(code),
+	}, CacheOptions{TTL: otpLoginExpiry}); err != nil {
+		fmt.Printf("Failed to store sign-in code: %v\n", err)
+		return 0, ErrOTPUnavailable
+	}
+	o.redis.Delete(o.attemptsKey(phone))
+
+	if err := o.smsService.SendLoginCodeSMS(phone, code); err != nil {
+		return 0, fmt.Errorf("failed to send sign-in code: %w", err)
+	}
+
+	return 0, nil
+}
+
+// VerifyCode checks a sign-in code and returns the customer it was issued to.
+// Each number gets otpLoginMaxAttempts guesses per code.
+func (o *OTPLoginService) VerifyCode(phone, code string) (*models.User, error) {
+	phone = cleanPhoneNumber(phone)
+
+	var stored otpLoginCode
+	if err := o.redis.Get(o.codeKey(phone), &stored); err != nil {
+		return nil, ErrOTPInvalid
+	}
+
+	attempts, err := o.redis.Increment(o.attemptsKey(phone))
+	if err != nil {
+		return nil, ErrOTPUnavailable
+	}
+	if attempts == 1 {
+		o.redis.SetExpiry(o.attemptsKey(phone), otpLoginExpiry)
+	}
+	if attempts > otpLoginMaxAttempts {
+		o.redis.Delete(o.codeKey(phone))
+		return nil, ErrOTPTooManyAttempts
+	}
+
+	if subtle.ConstantTimeCompare([]byte(stored.CodeHash), []byte(utils.HashToken(code))) != 1 {
+		return nil, ErrOTPInvalid
+	}
+
+	// Codes are single use
+	o.redis.Delete(o.codeKey(phone))
+	o.redis.Delete(o.attemptsKey(phone))
+
+	var user models.User
+	if err := config.DB.Where("id = ?", stored.UserID).First(&user).Error; err != nil {
+		return nil, ErrOTPInvalid
+	}
+
+	return &user, nil
+}
+
+// findCustomer resolves a number to the one customer account that has
+// verified it. Unverified numbers are never accepted, since a mistyped number
+// would otherwise let its real owner sign in to someone else's account.
+func (o *OTPLoginService) findCustomer(phone string) (*models.User, error) {
+	var users []models.User
+	if err := config.DB.
+		Where("regexp_replace(phone, '[^0-9+]', '', 'g') = ? AND role = ?", phone, models.RoleCustomer).
+		Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	var verified []models.User
+	for _, user := range users {
+		if user.IsPhoneVerified() {
+			verified = append(verified, user)
+		}
+	}
+	if len(verified) == 1 {
+		return &verified[0], nil
+	}
+
+	return nil, ErrOTPInvalidPhone
+}
+
+func (o *OTPLoginService) codeKey(phone string) string {
+	return "otp:login:code:" + phone
+}
+
+func (o *OTPLoginService) attemptsKey(phone string) string {
+	return "otp:login:attempts:" + phone
+}
+
+func (o *OTPLoginService) requestsKey(phone string) string {
+	return "otp:login:requests:" + phone
+}
+
+func (o *OTPLoginService) cooldownKey(phone string) string {
+	return "otp:login:cooldown:" + phone
+}
@@ -0,0 +1,186 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"backend/config"
+	"backend/models"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrPriceTierExists      = errors.New("a tier for this quantity and customer group already exists")
+	ErrInvalidPriceTier     = errors.New("invalid price tier")
+	ErrInvalidSale          = errors.New("invalid sale")
+	ErrInvalidCustomerGroup = errors.New("customer group must be 2-32 lowercase letters, digits or underscores")
+)
+
+var customerGroupPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,31}$`)
+
+// PricingService works out unit prices from quantity breaks and the
+// customer's group
+type PricingService struct{}
+
+func NewPricingService() *PricingService {
+	return &PricingService{}
+}
+
+// ValidCustomerGroup reports whether a customer group name is well formed
+func ValidCustomerGroup(group string) bool {
+	return customerGroupPattern.MatchString(group)
+}
+
+// CustomerGroup returns the pricing group of a user, or nil for none
+func (s *PricingService) CustomerGroup(userID string) *string {
+	var user models.User
+	if err := config.DB.Select("customer_group").First(&user, "id = ?", userID).Error; err != nil {
+		return nil
+	}
+	return user.CustomerGroup
+}
+
+// UnitPrice returns the price each for buying quantity of the item: the
+// lowest of its list price and every tier the quantity and group qualify for
+func (s *PricingService) UnitPrice(item *StockItem, quantity float64, customerGroup *string) (float64, error) {
+	price := item.Price()
+
+	query := s.tiersFor(item).Where("min_quantity <= ?", quantity)
+	if customerGroup != nil {
+		query = query.Where("customer_group IS NULL OR customer_group = ?", *customerGroup)
+	} else {
+		query = query.Where("customer_group IS NULL")
+	}
+
+	var tier models.PriceTier
+	err := query.Order("unit_price ASC").First(&tier).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return price, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch price tiers: %w", err)
+	}
+
+	if tier.UnitPrice < price {
+		price = tier.UnitPrice
+	}
+	return price, nil
+}
+
+// PublicTiers lists the quantity breaks open to every customer, for product pages
+func (s *PricingService) PublicTiers(productID uuid.UUID) ([]models.PriceTier, error) {
+	var tiers []models.PriceTier
+	if err := config.DB.Where("product_id = ? AND customer_group IS NULL", productID).
+		Order("variant_id ASC, min_quantity ASC").
+		Find(&tiers).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch price tiers: %w", err)
+	}
+	return tiers, nil
+}
+
+// ValidateTier checks a new or edited tier against its product
+func (s *PricingService) ValidateTier(tier *models.PriceTier) error {
+	if tier.MinQuantity <= 0 {
+		return fmt.Errorf("%w: min_quantity must be greater than 0", ErrInvalidPriceTier)
+	}
+
+	if tier.UnitPrice < 0 {
+		return fmt.Errorf("%w: unit_price must be non-negative", ErrInvalidPriceTier)
+	}
+
+	if tier.CustomerGroup != nil && !ValidCustomerGroup(*tier.CustomerGroup) {
+		return ErrInvalidCustomerGroup
+	}
+
+	if tier.VariantID != nil {
+		var count int64
+		if err := config.DB.Model(&models.ProductVariant{}).
+			Where("id = ? AND product_id = ?", *tier.VariantID, tier.ProductID).
+			Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check variant: %w", err)
+		}
+		if count == 0 {
+			return ErrVariantNotFound
+		}
+	}
+
+	query := config.DB.Model(&models.PriceTier{}).
+		Where("product_id = ? AND min_quantity = ?", tier.ProductID, tier.MinQuantity)
+	if tier.VariantID != nil {
+		query = query.Where("variant_id = ?", *tier.VariantID)
+	} else {
+		query = query.Where("variant_id IS NULL")
+	}
+	if tier.CustomerGroup != nil {
+		query = query.Where("customer_group = ?", *tier.CustomerGroup)
+	} else {
+		query = query.Where("customer_group IS NULL")
+	}
+	if tier.ID != uuid.Nil {
+		query = query.Where("id != ?", tier.ID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return fmt.Errorf("failed to check price tiers: %w", err)
+	}
+	if count > 0 {
+		return ErrPriceTierExists
+	}
+
+	return nil
+}
+
+// ValidateSale checks a sale against the list price it discounts
+func (s *PricingService) ValidateSale(listPrice float64, sale models.SalePricing) error {
+	if sale.SalePrice == nil {
+		return fmt.Errorf("%w: sale_price is required", ErrInvalidSale)
+	}
+
+	if *sale.SalePrice < 0 || *sale.SalePrice >= listPrice {
+		return fmt.Errorf("%w: sale_price must be below the list price of %.2f", ErrInvalidSale, listPrice)
+	}
+
+	if sale.SaleStartsAt != nil && sale.SaleEndsAt != nil && !sale.SaleEndsAt.After(*sale.SaleStartsAt) {
+		return fmt.Errorf("%w: the sale must end after it starts", ErrInvalidSale)
+	}
+
+	if sale.SaleEndsAt != nil && !sale.SaleEndsAt.After(time.Now()) {
+		return fmt.Errorf("%w: the sale has already ended", ErrInvalidSale)
+	}
+
+	return nil
+}
+
+// RecordPriceChange snapshots a product's or variant's prices after a change
+func (s *PricingService) RecordPriceChange(tx *gorm.DB, change models.PriceChange) error {
+	if err := tx.Create(&change).Error; err != nil {
+		return fmt.Errorf("failed to record price change: %w", err)
+	}
+	return nil
+}
+
+// PriceHistory lists a product's price changes, including its variants', newest first
+func (s *PricingService) PriceHistory(productID string) ([]models.PriceChange, error) {
+	var changes []models.PriceChange
+	if err := config.DB.Where("product_id = ?", productID).
+		Order("created_at DESC").
+		Find(&changes).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch price history: %w", err)
+	}
+	return changes, nil
+}
+
+// tiersFor selects the tiers for exactly this product or variant; variants
+// are priced separately, so product-level tiers do not apply to them
+func (s *PricingService) tiersFor(item *StockItem) *gorm.DB {
+	query := config.DB.Model(&models.PriceTier{}).Where("product_id = ?", item.Product.ID)
+	if item.Variant != nil {
+		return query.Where("variant_id = ?", item.Variant.ID)
+	}
+	return query.Where("variant_id IS NULL")
+}
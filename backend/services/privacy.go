@@ -0,0 +1,262 @@
+package services
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"backend/config"
+	"backend/models"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+)
+
+// erasedPlaceholder replaces free-text personal data on records kept after erasure
+const erasedPlaceholder = "[erased]"
+
+var ErrErasureBlocked = errors.New("account has open orders or service requests")
+
+type PrivacyService struct{}
+
+// UserDataExport is everything the store holds about a user, as handed to
+// them on request
+type UserDataExport struct {
+	ExportedAt      time.Time               `json:"exported_at"`
+	Profile         models.User             `json:"profile"`
+	Addresses       []models.Address        `json:"addresses"`
+	Orders          []models.Order          `json:"orders"`
+	Payments        []PaymentExport         `json:"payments"`
+	ServiceRequests []models.ServiceRequest `json:"service_requests"`
+	Notifications   []models.Notification   `json:"notifications"`
+	Reviews         []models.Review         `json:"reviews"`
+}
+
+// PaymentExport is a payment without its order and user relations, which
+// are not loaded for an export and would otherwise appear as empty records
+type PaymentExport struct {
+	models.Base
+	OrderID   uuid.UUID            `json:"order_id"`
+	UserID    uuid.UUID            `json:"user_id"`
+	Provider  string               `json:"provider"`
+	Reference string               `json:"reference"`
+	Amount    float64              `json:"amount"`
+	Status    models.PaymentStatus `json:"status"`
+	PaidAt    *time.Time           `json:"paid_at"`
+}
+
+func NewPrivacyService() *PrivacyService {
+	return &PrivacyService{}
+}
+
+// Export collects the user's personal data
+func (p *PrivacyService) Export(userID string) (*UserDataExport, error) {
+	export := &UserDataExport{ExportedAt: time.Now()}
+
+	if err := config.DB.Where("id = ?", userID).First(&export.Profile).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch profile: %w", err)
+	}
+
+	queries := []struct {
+		name string
+		dest interface{}
+		db   *gorm.DB
+	}{
+		{"addresses", &export.Addresses, config.DB},
+		{"orders", &export.Orders, config.DB.Preload("OrderItems")},
+		{"service requests", &export.ServiceRequests, config.DB},
+		{"notifications", &export.Notifications, config.DB},
+		{"reviews", &export.Reviews, config.DB},
+	}
+
+	for _, query := range queries {
+		if err := query.db.Where("user_id = ?", userID).Order("created_at ASC").Find(query.dest).Error; err != nil {
+			return nil, fmt.Errorf("failed to fetch %s: %w", query.name, err)
+		}
+	}
+
+	// Payments are found through the user's orders, which every payment
+	// belongs to, rather than relying on the payment's own user ID
+	if err := config.DB.Model(&models.Payment{}).
+		Where("order_id IN (?)", config.DB.Model(&models.Order{}).Select("id").Where("user_id = ?", userID)).
+		Order("created_at ASC").Find(&export.Payments).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch payments: %w", err)
+	}
+
+	return export, nil
+}
+
+// ExportZip packages an export as a ZIP archive with one JSON file per section
+func (p *PrivacyService) ExportZip(export *UserDataExport) ([]byte, error) {
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"addresses.json", export.Addresses},
+		{"orders.json", export.Orders},
+		{"payments.json", export.Payments},
+		{"service_requests.json", export.ServiceRequests},
+		{"notifications.json", export.Notifications},
+		{"reviews.json", export.Reviews},
+	}
+
+	var buf bytes.Buffer
+	archive := zip.NewWriter(&buf)
+
+	for _, file := range files {
+		writer, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: export.ExportedAt,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to add %s: %w", file.name, err)
+		}
+
+		encoder := json.NewEncoder(writer)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", file.name, err)
+		}
+	}
+
+	if err := archive.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Erase anonymizes the user's personal data. Orders and payments are kept
+// for accounting, with delivery addresses reduced to city and country.
+func (p *PrivacyService) Erase(user *models.User) error {
+	var openOrders int64
+	if err := config.DB.Model(&models.Order{}).
+		Where("user_id = ? AND status IN ?", user.ID, []models.OrderStatus{
+			models.OrderStatusPending, models.OrderStatusConfirmed, models.OrderStatusShipped,
+		}).
+		Count(&openOrders).Error; err != nil {
+		return fmt.Errorf("failed to check open orders: %w", err)
+	}
+
+	var openRequests int64
+	if err := config.DB.Model(&models.ServiceRequest{}).
+		Where("user_id = ? AND status NOT IN ?", user.ID, []models.ServiceStatus{
+			models.ServiceStatusCompleted, models.ServiceStatusBilled, models.ServiceStatusCancelled,
+		}).
+		Count(&openRequests).Error; err != nil {
+		return fmt.Errorf("failed to check open service requests: %w", err)
+	}
+
+	if openOrders > 0 || openRequests > 0 {
+		return ErrErasureBlocked
+	}
+
+	reviews := NewReviewService()
+	var reviewPhotos []string
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var orders []models.Order
+		if err := tx.Where("user_id = ?", user.ID).Find(&orders).Error; err != nil {
+			return fmt.Errorf("failed to fetch orders: %w", err)
+		}
+		for _, order := range orders {
+			address := models.AddressData{
+				Label:   erasedPlaceholder,
+				Line:    erasedPlaceholder,
+				City:    order.AddressJSON.City,
+				Country: order.AddressJSON.Country,
+			}
+			updates := map[string]interface{}{"address_json": address}
+			// Keep the kind of service ordered but not the customer's details
+			if order.ServiceRequest != nil {
+				updates["service_request"] = models.ServiceData{
+					Type:    order.ServiceRequest.Type,
+					Details: map[string]interface{}{},
+				}
+			}
+			if err := tx.Model(&order).Updates(updates).Error; err != nil {
+				return fmt.Errorf("failed to anonymize order: %w", err)
+			}
+		}
+
+		if err := tx.Model(&models.ServiceRequest{}).Where("user_id = ?", user.ID).Updates(map[string]interface{}{
+			"location":     erasedPlaceholder,
+			"instructions": "",
+			"details":      "{}",
+		}).Error; err != nil {
+			return fmt.Errorf("failed to anonymize service requests: %w", err)
+		}
+
+		if err := tx.Where("cart_id IN (?)", tx.Model(&models.Cart{}).Select("id").Where("user_id = ?", user.ID)).
+			Delete(&models.CartItem{}).Error; err != nil {
+			return fmt.Errorf("failed to delete cart items: %w", err)
+		}
+
+		var err error
+		if reviewPhotos, err = reviews.DeleteUserReviews(tx, user.ID); err != nil {
+			return err
+		}
+
+		// Records that only exist to serve the account holder are removed outright
+		for _, record := range []interface{}{
+			&models.Cart{},
+			&models.Wishlist{},
+			&models.Address{},
+			&models.Notification{},
+			&models.RecoveryCode{},
+			&models.VerificationCode{},
+		} {
+			if err := tx.Where("user_id = ?", user.ID).Delete(record).Error; err != nil {
+				return fmt.Errorf("failed to delete personal records: %w", err)
+			}
+		}
+
+		now := time.Now()
+		if err := tx.Model(&models.Session{}).
+			Where("user_id = ? AND revoked_at IS NULL", user.ID).
+			Updates(map[string]interface{}{
+				"revoked_at":     now,
+				"revoked_reason": models.SessionRevokedErased,
+			}).Error; err != nil {
+			return fmt.Errorf("failed to revoke sessions: %w", err)
+		}
+
+		if err := tx.Model(&models.APIKey{}).
+			Where("created_by_id = ? AND revoked_at IS NULL", user.ID).
+			Update("revoked_at", now).Error; err != nil {
+			return fmt.Errorf("failed to revoke API keys: %w", err)
+		}
+
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"email":                fmt.Sprintf("erased-%s@erased.invalid", user.ID),
+			"full_name":            "Erased User",
+			"phone":                nil,
+			"password_hash":        erasedPlaceholder,
+			"is_active":            false,
+			"email_verified_at":    nil,
+			"phone_verified_at":    nil,
+			"locked_until":         nil,
+			"reset_token":          nil,
+			"reset_token_expiry":   nil,
+			"two_factor_enabled":   false,
+			"two_factor_secret":    nil,
+			"two_factor_last_step": 0,
+			"erased_at":            now,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to anonymize user: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	go reviews.deletePhotos(reviewPhotos)
+	return nil
+}
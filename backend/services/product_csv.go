@@ -0,0 +1,596 @@
+package services
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"backend/config"
+	"backend/models"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// maxImportRows keeps a single import to a size that finishes in minutes
+	maxImportRows = 10000
+	// importProgressEvery is how many rows pass between progress updates
+	importProgressEvery = 50
+)
+
+// ProductCSVColumns is the column order of product exports. Imports accept
+// the same columns in any order; only sku is required in the header.
+var ProductCSVColumns = []string{
+	"sku", "name", "slug", "category_slug", "brand_slug", "description", "price", "stock_quantity",
+	"unit", "unit_size", "quantity_step", "purchase_unit", "purchase_unit_size",
+	"images", "is_active",
+}
+
+var ErrInvalidCSV = errors.New("invalid CSV")
+
+var slugInvalidChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// ProductCSVService imports and exports the product catalog as CSV
+type ProductCSVService struct {
+	pricing *PricingService
+}
+
+// ProductImportRow is one data row of an import file, keyed by column name
+type ProductImportRow struct {
+	Line   int
+	Values map[string]string
+}
+
+// productImportContext holds what every row is validated against
+type productImportContext struct {
+	categories  map[string]uuid.UUID
+	brands      map[string]uuid.UUID
+	existing    map[string]models.Product
+	variantSKUs map[string]bool
+	seenSKUs    map[string]int
+	slugOwners  map[string]string
+	seenSlugs   map[string]string
+}
+
+func NewProductCSVService() *ProductCSVService {
+	return &ProductCSVService{
+		pricing: NewPricingService(),
+	}
+}
+
+// ParseImport reads an import file up front so problems with the file as a
+// whole are reported to the uploader rather than in the job
+func (s *ProductCSVService) ParseImport(r io.Reader) ([]ProductImportRow, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: missing header row", ErrInvalidCSV)
+	}
+
+	known := make(map[string]bool, len(ProductCSVColumns))
+	for _, column := range ProductCSVColumns {
+		known[column] = true
+	}
+
+	columns := make([]string, len(header))
+	hasSKU := false
+	for i, column := range header {
+		column = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(column, "\ufeff")))
+		if !known[column] {
+			return nil, fmt.Errorf("%w: unknown column %q", ErrInvalidCSV, column)
+		}
+		columns[i] = column
+		hasSKU = hasSKU || column == "sku"
+	}
+	if !hasSKU {
+		return nil, fmt.Errorf("%w: the sku column is required", ErrInvalidCSV)
+	}
+
+	var rows []ProductImportRow
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidCSV, err)
+		}
+
+		if len(rows) >= maxImportRows {
+			return nil, fmt.Errorf("%w: at most %d rows can be imported at once", ErrInvalidCSV, maxImportRows)
+		}
+
+		// Quoted cells can span lines, so ask the reader where the row started
+		line, _ := reader.FieldPos(0)
+		values := make(map[string]string, len(columns))
+		for i, column := range columns {
+			values[column] = strings.TrimSpace(record[i])
+		}
+		rows = append(rows, ProductImportRow{Line: line, Values: values})
+	}
+
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: the file has no product rows", ErrInvalidCSV)
+	}
+
+	return rows, nil
+}
+
+// StartImport records an import job and runs it in the background
+func (s *ProductCSVService) StartImport(rows []ProductImportRow, fileName string, dryRun bool, createdByID *uuid.UUID) (*models.ProductImportJob, error) {
+	job := models.ProductImportJob{
+		FileName:    fileName,
+		DryRun:      dryRun,
+		Status:      models.ImportJobPending,
+		TotalRows:   len(rows),
+		CreatedByID: createdByID,
+	}
+
+	if err := config.DB.Create(&job).Error; err != nil {
+		return nil, fmt.Errorf("failed to create import job: %w", err)
+	}
+
+	go s.runImport(job, rows)
+
+	return &job, nil
+}
+
+// runImport validates every row, and unless the job is a dry run saves the
+// valid ones. Invalid rows are skipped and reported; they never stop the job.
+func (s *ProductCSVService) runImport(job models.ProductImportJob, rows []ProductImportRow) {
+	defer func() {
+		if r := recover(); r != nil {
+			s.finishImport(&job, fmt.Sprintf("import stopped unexpectedly: %v", r))
+		}
+	}()
+
+	now := time.Now()
+	job.Status = models.ImportJobRunning
+	job.StartedAt = &now
+	config.DB.Model(&job).Updates(map[string]interface{}{"status": job.Status, "started_at": now})
+
+	ctx, err := s.loadImportContext(rows)
+	if err != nil {
+		s.finishImport(&job, err.Error())
+		return
+	}
+
+	for i, row := range rows {
+		product, isNew, rowErrors := s.planRow(row, ctx)
+		switch {
+		case len(rowErrors) > 0:
+			job.RowErrors = append(job.RowErrors, rowErrors...)
+			job.FailedCount++
+		case job.DryRun:
+			s.countRow(&job, isNew)
+		default:
+			if err := s.saveRow(product, row, isNew, job.CreatedByID); err != nil {
+				job.RowErrors = append(job.RowErrors, models.ImportRowError{Row: row.Line, SKU: product.SKU, Message: err.Error()})
+				job.FailedCount++
+			} else {
+				s.countRow(&job, isNew)
+			}
+		}
+
+		job.ProcessedRows = i + 1
+		if job.ProcessedRows%importProgressEvery == 0 {
+			config.DB.Model(&job).Updates(map[string]interface{}{
+				"processed_rows": job.ProcessedRows,
+				"created_count":  job.CreatedCount,
+				"updated_count":  job.UpdatedCount,
+				"failed_count":   job.FailedCount,
+			})
+		}
+	}
+
+	s.finishImport(&job, "")
+}
+
+func (s *ProductCSVService) countRow(job *models.ProductImportJob, isNew bool) {
+	if isNew {
+		job.CreatedCount++
+	} else {
+		job.UpdatedCount++
+	}
+}
+
+func (s *ProductCSVService) finishImport(job *models.ProductImportJob, failure string) {
+	now := time.Now()
+	status := models.ImportJobCompleted
+	if failure != "" {
+		status = models.ImportJobFailed
+	}
+
+	if err := config.DB.Model(job).Updates(map[string]interface{}{
+		"status":         status,
+		"processed_rows": job.ProcessedRows,
+		"created_count":  job.CreatedCount,
+		"updated_count":  job.UpdatedCount,
+		"failed_count":   job.FailedCount,
+		"row_errors":     job.RowErrors,
+		"error":          failure,
+		"finished_at":    now,
+	}).Error; err != nil {
+		fmt.Printf("Failed to save import job %s: %v\n", job.ID, err)
+	}
+
+	if !job.DryRun && job.CreatedCount+job.UpdatedCount > 0 {
+		NewCatalogCacheService().Invalidate()
+	}
+}
+
+// loadImportContext fetches categories, brands, the products and variants the
+// file's SKUs refer to and the owners of the slugs its rows would take, in a
+// few queries rather than per row
+func (s *ProductCSVService) loadImportContext(rows []ProductImportRow) (*productImportContext, error) {
+	ctx := &productImportContext{
+		categories:  map[string]uuid.UUID{},
+		brands:      map[string]uuid.UUID{},
+		existing:    map[string]models.Product{},
+		variantSKUs: map[string]bool{},
+		seenSKUs:    map[string]int{},
+		slugOwners:  map[string]string{},
+		seenSlugs:   map[string]string{},
+	}
+
+	var categories []models.Category
+	if err := config.DB.Find(&categories).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch categories: %w", err)
+	}
+	for _, category := range categories {
+		ctx.categories[category.Slug] = category.ID
+	}
+
+	var brands []models.Brand
+	if err := config.DB.Find(&brands).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch brands: %w", err)
+	}
+	for _, brand := range brands {
+		ctx.brands[brand.Slug] = brand.ID
+	}
+
+	skus := make([]string, 0, len(rows))
+	slugs := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if sku := row.Values["sku"]; sku != "" {
+			skus = append(skus, sku)
+		}
+		if slug := row.Values["slug"]; slug != "" {
+			slugs = append(slugs, slug)
+		} else {
+			slugs = append(slugs, slugify(row.Values["name"]+" "+row.Values["sku"]))
+		}
+	}
+
+	var products []models.Product
+	if err := config.DB.Where("sku IN ?", skus).Find(&products).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch products: %w", err)
+	}
+	for _, product := range products {
+		ctx.existing[product.SKU] = product
+	}
+
+	var variantSKUs []string
+	if err := config.DB.Model(&models.ProductVariant{}).Where("sku IN ?", skus).Pluck("sku", &variantSKUs).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch variants: %w", err)
+	}
+	for _, sku := range variantSKUs {
+		ctx.variantSKUs[sku] = true
+	}
+
+	var owners []struct {
+		Slug string
+		SKU  string
+	}
+	if err := config.DB.Model(&models.Product{}).Select("slug, sku").Where("slug IN ?", slugs).Scan(&owners).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch product slugs: %w", err)
+	}
+	for _, owner := range owners {
+		ctx.slugOwners[owner.Slug] = owner.SKU
+	}
+
+	return ctx, nil
+}
+
+// planRow validates a row and returns the product as it would be saved.
+// Columns missing from the file, and empty cells, leave existing values as
+// they are.
+func (s *ProductCSVService) planRow(row ProductImportRow, ctx *productImportContext) (*models.Product, bool, []models.ImportRowError) {
+	sku := row.Values["sku"]
+	var rowErrors []models.ImportRowError
+	fail := func(field, message string) {
+		rowErrors = append(rowErrors, models.ImportRowError{Row: row.Line, SKU: sku, Field: field, Message: message})
+	}
+
+	if sku == "" {
+		fail("sku", "sku is required")
+		return nil, false, rowErrors
+	}
+	if line, seen := ctx.seenSKUs[sku]; seen {
+		fail("sku", fmt.Sprintf("duplicate of row %d", line))
+		return nil, false, rowErrors
+	}
+	ctx.seenSKUs[sku] = row.Line
+
+	product, exists := ctx.existing[sku]
+	isNew := !exists
+	if isNew {
+		if ctx.variantSKUs[sku] {
+			fail("sku", "sku is already used by a product variant")
+		}
+		product = models.Product{
+			SKU:      sku,
+			IsActive: true,
+			Measure: models.Measure{
+				Unit:             models.UnitPiece,
+				UnitSize:         1,
+				QuantityStep:     1,
+				PurchaseUnitSize: 1,
+			},
+		}
+		for _, field := range []string{"name", "category_slug", "price"} {
+			if row.Values[field] == "" {
+				fail(field, field+" is required for new products")
+			}
+		}
+	}
+
+	value := func(field string) (string, bool) {
+		v, ok := row.Values[field]
+		return v, ok && v != ""
+	}
+	number := func(field string) (float64, bool) {
+		v, ok := value(field)
+		if !ok {
+			return 0, false
+		}
+		n, err := strconv.ParseFloat(v, 64)
+		if err != nil || n < 0 {
+			fail(field, field+" must be a non-negative number")
+			return 0, false
+		}
+		return n, true
+	}
+
+	if v, ok := value("name"); ok {
+		product.Name = v
+	}
+	if v, ok := value("description"); ok {
+		product.Description = v
+	}
+	if v, ok := value("category_slug"); ok {
+		if id, found := ctx.categories[v]; found {
+			product.CategoryID = id
+		} else {
+			fail("category_slug", fmt.Sprintf("category %q does not exist", v))
+		}
+	}
+	if v, ok := value("brand_slug"); ok {
+		if id, found := ctx.brands[v]; found {
+			product.BrandID = &id
+		} else {
+			fail("brand_slug", fmt.Sprintf("brand %q does not exist", v))
+		}
+	}
+	if n, ok := number("price"); ok {
+		product.Price = n
+	}
+	if n, ok := number("stock_quantity"); ok {
+		if product.IsBundle {
+			fail("stock_quantity", ErrBundleStock.Error())
+		} else {
+			product.StockQuantity = models.RoundQuantity(n)
+		}
+	}
+	if v, ok := value("unit"); ok {
+		product.Unit = models.UnitOfMeasure(strings.ToLower(v))
+	}
+	if n, ok := number("unit_size"); ok {
+		product.UnitSize = n
+	}
+	if n, ok := number("quantity_step"); ok {
+		product.QuantityStep = models.RoundQuantity(n)
+	}
+	if v, ok := value("purchase_unit"); ok {
+		product.PurchaseUnit = v
+	}
+	if n, ok := number("purchase_unit_size"); ok {
+		product.PurchaseUnitSize = models.RoundQuantity(n)
+	}
+	if err := product.Measure.Validate(); err != nil {
+		fail("unit", err.Error())
+	} else if product.IsBundle && !product.IsCountable() {
+		fail("unit", "bundles must be sold by the piece or box")
+	}
+	if v, ok := value("images"); ok {
+		var images models.ImagesArray
+		for _, image := range strings.Split(v, "|") {
+			image = strings.TrimSpace(image)
+			if image == "" {
+				continue
+			}
+			if !strings.HasPrefix(image, "http://") && !strings.HasPrefix(image, "https://") {
+				fail("images", fmt.Sprintf("%q is not an http(s) URL", image))
+				continue
+			}
+			images = append(images, image)
+		}
+		product.ImagesJSON = images
+	}
+	if v, ok := value("is_active"); ok {
+		active, err := strconv.ParseBool(v)
+		if err != nil {
+			fail("is_active", "is_active must be true or false")
+		}
+		product.IsActive = active
+	}
+
+	if v, ok := value("slug"); ok {
+		product.Slug = v
+	} else if isNew {
+		product.Slug = slugify(product.Name + " " + sku)
+	}
+	if product.Slug == "" {
+		fail("slug", "slug is required")
+	} else if s.slugTaken(product.Slug, sku, ctx) {
+		fail("slug", fmt.Sprintf("slug %q is already used by another product", product.Slug))
+	} else {
+		ctx.seenSlugs[product.Slug] = sku
+	}
+
+	return &product, isNew, rowErrors
+}
+
+// slugTaken reports whether another product, in the database or earlier in
+// the file, already has the slug
+func (s *ProductCSVService) slugTaken(slug, sku string, ctx *productImportContext) bool {
+	if owner, seen := ctx.seenSlugs[slug]; seen && owner != sku {
+		return true
+	}
+
+	owner, exists := ctx.slugOwners[slug]
+	return exists && owner != sku
+}
+
+// saveRow creates or updates a product, recording its price in the price
+// history when it is new or has changed. Updates write only the columns the
+// row sets, so changes made while the import runs, such as stock sold or an
+// admin's edits, are kept.
+func (s *ProductCSVService) saveRow(product *models.Product, row ProductImportRow, isNew bool, createdByID *uuid.UUID) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		priceChanged := isNew
+		salePricing := product.SalePricing
+		if isNew {
+			if err := tx.Create(product).Error; err != nil {
+				return fmt.Errorf("failed to create product: %w", err)
+			}
+			// GORM skips false for columns with a default, so set it explicitly
+			if !product.IsActive {
+				if err := tx.Model(product).Update("is_active", false).Error; err != nil {
+					return fmt.Errorf("failed to create product: %w", err)
+				}
+			}
+		} else {
+			var current models.Product
+			if err := tx.First(&current, "id = ?", product.ID).Error; err != nil {
+				return fmt.Errorf("failed to update product: %w", err)
+			}
+
+			updates := importUpdates(product, row)
+			if _, ok := updates["stock_quantity"]; ok && current.IsBundle {
+				return ErrBundleStock
+			}
+			if len(updates) > 0 {
+				if err := tx.Model(&current).Updates(updates).Error; err != nil {
+					return fmt.Errorf("failed to update product: %w", err)
+				}
+			}
+
+			_, hasPrice := updates["price"]
+			priceChanged = hasPrice && current.Price != product.Price
+			salePricing = current.SalePricing
+		}
+
+		if !priceChanged {
+			return nil
+		}
+
+		return s.pricing.RecordPriceChange(tx, models.PriceChange{
+			ProductID:   product.ID,
+			Price:       product.Price,
+			SalePricing: salePricing,
+			ChangedByID: createdByID,
+		})
+	})
+}
+
+// importUpdates returns the columns an import row sets on an existing
+// product, with the values planRow validated
+func importUpdates(product *models.Product, row ProductImportRow) map[string]interface{} {
+	columns := []struct {
+		field  string
+		column string
+		value  interface{}
+	}{
+		{"name", "name", product.Name},
+		{"slug", "slug", product.Slug},
+		{"category_slug", "category_id", product.CategoryID},
+		{"brand_slug", "brand_id", product.BrandID},
+		{"description", "description", product.Description},
+		{"price", "price", product.Price},
+		{"stock_quantity", "stock_quantity", product.StockQuantity},
+		{"unit", "unit", product.Unit},
+		{"unit_size", "unit_size", product.UnitSize},
+		{"quantity_step", "quantity_step", product.QuantityStep},
+		{"purchase_unit", "purchase_unit", product.PurchaseUnit},
+		{"purchase_unit_size", "purchase_unit_size", product.PurchaseUnitSize},
+		{"images", "images_json", product.ImagesJSON},
+		{"is_active", "is_active", product.IsActive},
+	}
+
+	updates := map[string]interface{}{}
+	for _, c := range columns {
+		if row.Values[c.field] != "" {
+			updates[c.column] = c.value
+		}
+	}
+
+	return updates
+}
+
+// WriteExport writes products as CSV in the ProductCSVColumns layout, which
+// can be edited and imported again
+func (s *ProductCSVService) WriteExport(w io.Writer, products []models.Product) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(ProductCSVColumns); err != nil {
+		return err
+	}
+
+	formatNumber := func(n float64) string {
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	}
+
+	for _, product := range products {
+		brandSlug := ""
+		if product.Brand != nil {
+			brandSlug = product.Brand.Slug
+		}
+
+		if err := writer.Write([]string{
+			product.SKU,
+			product.Name,
+			product.Slug,
+			product.Category.Slug,
+			brandSlug,
+			product.Description,
+			strconv.FormatFloat(product.Price, 'f', 2, 64),
+			formatNumber(product.StockQuantity),
+			string(product.Unit),
+			formatNumber(product.UnitSize),
+			formatNumber(product.QuantityStep),
+			product.PurchaseUnit,
+			formatNumber(product.PurchaseUnitSize),
+			strings.Join(product.ImagesJSON, "|"),
+			strconv.FormatBool(product.IsActive),
+		}); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
+
+// slugify turns a name into a URL slug, e.g. "PVC Pipe 50mm" to "pvc-pipe-50mm"
+func slugify(name string) string {
+	return strings.Trim(slugInvalidChars.ReplaceAllString(strings.ToLower(name), "-"), "-")
+}
@@ -0,0 +1,419 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"backend/config"
+	"backend/models"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	// relatedProductsPerProduct is how many computed related products are kept per product
+	relatedProductsPerProduct = 12
+	// minCoPurchases ignores pairs bought together only once, which are mostly noise
+	minCoPurchases = 2
+	// relatedProductsRefreshKey is claimed by the replica that runs a refresh
+	relatedProductsRefreshKey = "related-products:refresh"
+)
+
+var ErrInvalidRelatedOverride = errors.New("invalid related product override")
+
+// RecommendationService computes "frequently bought together" products from
+// order history and merges them with admin-curated overrides
+type RecommendationService struct {
+	refreshInterval time.Duration
+	lookback        time.Duration
+}
+
+// RelatedProductsAdmin is everything that decides a product's related
+// products, for the admin screen that curates them
+type RelatedProductsAdmin struct {
+	Computed  []models.RelatedProduct         `json:"computed"`
+	Overrides []models.RelatedProductOverride `json:"overrides"`
+	Effective []models.Product                `json:"effective"`
+}
+
+func NewRecommendationService() *RecommendationService {
+	refreshInterval, err := time.ParseDuration(os.Getenv("RELATED_PRODUCTS_REFRESH_INTERVAL"))
+	if err != nil || refreshInterval <= 0 {
+		refreshInterval = 6 * time.Hour
+	}
+
+	lookback, err := time.ParseDuration(os.Getenv("RELATED_PRODUCTS_LOOKBACK"))
+	if err != nil || lookback <= 0 {
+		lookback = 180 * 24 * time.Hour
+	}
+
+	return &RecommendationService{
+		refreshInterval: refreshInterval,
+		lookback:        lookback,
+	}
+}
+
+// RunPeriodically refreshes related products now and then on every refresh
+// interval. It never returns, so run it in its own goroutine.
+func (r *RecommendationService) RunPeriodically() {
+	ticker := time.NewTicker(r.refreshInterval)
+	defer ticker.Stop()
+
+	for {
+		if r.claimRefresh() {
+			if count, err := r.Refresh(); err != nil {
+				fmt.Printf("Failed to refresh related products: %v\n", err)
+			} else {
+				fmt.Printf("Refreshed related products: %d pairs\n", count)
+			}
+		}
+		<-ticker.C
+	}
+}
+
+// claimRefresh reports whether this replica should run the scheduled
+// refresh. The first replica to claim it holds the claim for most of an
+// interval, so replicas ticking at other times skip it, while its own next
+// tick finds the claim expired. Without Redis every replica refreshes, as a
+// single instance would.
+func (r *RecommendationService) claimRefresh() bool {
+	hostname, _ := os.Hostname()
+
+	claimed, err := SharedRedisService().SetIfAbsent(relatedProductsRefreshKey, hostname, r.refreshInterval*9/10)
+	if err != nil {
+		fmt.Printf("Failed to claim related products refresh: %v\n", err)
+		return true
+	}
+	return claimed
+}
+
+// Refresh recomputes co-purchase statistics from orders placed within the
+// lookback window, excluding cancelled ones, and replaces the stored
+// related products. Pairs are ranked by the number of orders containing
+// both products; the score is the share of the product's orders that also
+// contained the related product.
+func (r *RecommendationService) Refresh() (int, error) {
+	var pairs []models.RelatedProduct
+	if err := config.DB.Raw(`
+		WITH product_orders AS (
+			SELECT DISTINCT order_items.order_id, order_items.product_id
+			FROM order_items
+			JOIN orders ON orders.id = order_items.order_id
+			WHERE orders.status <> @cancelled AND orders.created_at >= @since
+		),
+		totals AS (
+			SELECT product_id, COUNT(*) AS orders FROM product_orders GROUP BY product_id
+		),
+		pairs AS (
+			SELECT a.product_id, b.product_id AS related_product_id, COUNT(*) AS co_purchases
+			FROM product_orders a
+			JOIN product_orders b ON b.order_id = a.order_id AND b.product_id <> a.product_id
+			GROUP BY a.product_id, b.product_id
+			HAVING COUNT(*) >= @min_co_purchases
+		),
+		ranked AS (
+			SELECT pairs.product_id, pairs.related_product_id, pairs.co_purchases,
+				pairs.co_purchases::float / totals.orders AS score,
+				ROW_NUMBER() OVER (
+					PARTITION BY pairs.product_id
+					ORDER BY pairs.co_purchases DESC, pairs.related_product_id
+				) AS rank
+			FROM pairs
+			JOIN totals ON totals.product_id = pairs.product_id
+		)
+		SELECT product_id, related_product_id, co_purchases, score, rank
+		FROM ranked
+		WHERE rank <= @per_product`,
+		map[string]interface{}{
+			"cancelled":        models.OrderStatusCancelled,
+			"since":            time.Now().Add(-r.lookback),
+			"min_co_purchases": minCoPurchases,
+			"per_product":      relatedProductsPerProduct,
+		},
+	).Scan(&pairs).Error; err != nil {
+		return 0, fmt.Errorf("failed to compute co-purchases: %w", err)
+	}
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.RelatedProduct{}).Error; err != nil {
+			return fmt.Errorf("failed to clear related products: %w", err)
+		}
+
+		if len(pairs) == 0 {
+			return nil
+		}
+
+		if err := tx.CreateInBatches(&pairs, 500).Error; err != nil {
+			return fmt.Errorf("failed to store related products: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Product pages list related products
+	NewCatalogCacheService().Invalidate()
+
+	return len(pairs), nil
+}
+
+// Related returns up to limit active products related to the product:
+// pinned products first, then co-purchased ones, topped up with other
+// products from the same category while co-purchase data is thin
+func (r *RecommendationService) Related(product *models.Product, limit int) ([]models.Product, error) {
+	ids, err := r.relatedIDs(product.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	related, err := r.activeProducts(ids, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(related) >= limit {
+		return related, nil
+	}
+
+	exclude := []uuid.UUID{product.ID}
+	for _, p := range related {
+		exclude = append(exclude, p.ID)
+	}
+
+	hidden, err := r.hiddenIDs(product.ID)
+	if err != nil {
+		return nil, err
+	}
+	exclude = append(exclude, hidden...)
+
+	var fillers []models.Product
+	if err := config.DB.Where("category_id = ? AND is_active = ? AND id NOT IN ?", product.CategoryID, true, exclude).
+		Order("rating_count DESC, created_at DESC").
+		Limit(limit - len(related)).
+		Find(&fillers).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch category products: %w", err)
+	}
+
+	return append(related, fillers...), nil
+}
+
+// ForCart recommends products bought together with anything in the cart.
+// Products related to several cart items, or ranked highly, come first.
+func (r *RecommendationService) ForCart(productIDs []uuid.UUID, limit int) ([]models.Product, error) {
+	inCart := make(map[uuid.UUID]bool, len(productIDs))
+	for _, id := range productIDs {
+		inCart[id] = true
+	}
+
+	relatedByProduct, err := r.relatedIDsFor(productIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	weights := map[uuid.UUID]float64{}
+	for _, related := range relatedByProduct {
+		for position, relatedID := range related {
+			if !inCart[relatedID] {
+				weights[relatedID] += 1 / float64(position+1)
+			}
+		}
+	}
+
+	ids := make([]uuid.UUID, 0, len(weights))
+	for id := range weights {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if weights[ids[i]] != weights[ids[j]] {
+			return weights[ids[i]] > weights[ids[j]]
+		}
+		return ids[i].String() < ids[j].String()
+	})
+
+	return r.activeProducts(ids, limit)
+}
+
+// GetAdminView returns a product's computed and curated related products
+// along with the list shoppers see
+func (r *RecommendationService) GetAdminView(product *models.Product, limit int) (*RelatedProductsAdmin, error) {
+	view := &RelatedProductsAdmin{}
+
+	if err := config.DB.Where("product_id = ?", product.ID).Order("rank ASC").Find(&view.Computed).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch related products: %w", err)
+	}
+
+	if err := config.DB.Where("product_id = ?", product.ID).Order("type ASC, position ASC").Find(&view.Overrides).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch overrides: %w", err)
+	}
+
+	effective, err := r.Related(product, limit)
+	if err != nil {
+		return nil, err
+	}
+	view.Effective = effective
+
+	return view, nil
+}
+
+// SetOverrides replaces a product's overrides. Pinned products keep the
+// order given.
+func (r *RecommendationService) SetOverrides(productID uuid.UUID, pinned, hidden []uuid.UUID) error {
+	seen := map[uuid.UUID]bool{}
+	for _, id := range append(append([]uuid.UUID{}, pinned...), hidden...) {
+		if id == productID {
+			return fmt.Errorf("%w: a product cannot be related to itself", ErrInvalidRelatedOverride)
+		}
+		if seen[id] {
+			return fmt.Errorf("%w: product %s is listed more than once", ErrInvalidRelatedOverride, id)
+		}
+		seen[id] = true
+	}
+
+	if len(seen) > 0 {
+		ids := make([]uuid.UUID, 0, len(seen))
+		for id := range seen {
+			ids = append(ids, id)
+		}
+
+		var found int64
+		if err := config.DB.Model(&models.Product{}).Where("id IN ?", ids).Count(&found).Error; err != nil {
+			return fmt.Errorf("failed to check products: %w", err)
+		}
+		if found != int64(len(ids)) {
+			return fmt.Errorf("%w: unknown product", ErrInvalidRelatedOverride)
+		}
+	}
+
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("product_id = ?", productID).Delete(&models.RelatedProductOverride{}).Error; err != nil {
+			return fmt.Errorf("failed to clear overrides: %w", err)
+		}
+
+		var overrides []models.RelatedProductOverride
+		for position, id := range pinned {
+			overrides = append(overrides, models.RelatedProductOverride{
+				ProductID:        productID,
+				RelatedProductID: id,
+				Type:             models.RelatedOverridePinned,
+				Position:         position,
+			})
+		}
+		for _, id := range hidden {
+			overrides = append(overrides, models.RelatedProductOverride{
+				ProductID:        productID,
+				RelatedProductID: id,
+				Type:             models.RelatedOverrideHidden,
+			})
+		}
+
+		if len(overrides) == 0 {
+			return nil
+		}
+
+		if err := tx.Create(&overrides).Error; err != nil {
+			return fmt.Errorf("failed to save overrides: %w", err)
+		}
+		return nil
+	})
+}
+
+// DeleteForProduct removes computed pairs and overrides that mention the
+// product, on either side
+func (r *RecommendationService) DeleteForProduct(tx *gorm.DB, productID string) error {
+	for _, record := range []interface{}{&models.RelatedProduct{}, &models.RelatedProductOverride{}} {
+		if err := tx.Where("product_id = ? OR related_product_id = ?", productID, productID).Delete(record).Error; err != nil {
+			return fmt.Errorf("failed to delete related products: %w", err)
+		}
+	}
+	return nil
+}
+
+// relatedIDs returns pinned products in position order followed by
+// computed ones in rank order, leaving out hidden products
+func (r *RecommendationService) relatedIDs(productID uuid.UUID) ([]uuid.UUID, error) {
+	related, err := r.relatedIDsFor([]uuid.UUID{productID})
+	if err != nil {
+		return nil, err
+	}
+	return related[productID], nil
+}
+
+// relatedIDsFor works out relatedIDs for several products in two queries
+func (r *RecommendationService) relatedIDsFor(productIDs []uuid.UUID) (map[uuid.UUID][]uuid.UUID, error) {
+	related := make(map[uuid.UUID][]uuid.UUID, len(productIDs))
+	if len(productIDs) == 0 {
+		return related, nil
+	}
+
+	var overrides []models.RelatedProductOverride
+	if err := config.DB.Where("product_id IN ?", productIDs).Order("position ASC").Find(&overrides).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch overrides: %w", err)
+	}
+
+	var computed []models.RelatedProduct
+	if err := config.DB.Where("product_id IN ?", productIDs).Order("rank ASC").Find(&computed).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch related products: %w", err)
+	}
+
+	skip := map[uuid.UUID]map[uuid.UUID]bool{}
+	for _, override := range overrides {
+		if skip[override.ProductID] == nil {
+			skip[override.ProductID] = map[uuid.UUID]bool{}
+		}
+		skip[override.ProductID][override.RelatedProductID] = true
+		if override.Type == models.RelatedOverridePinned {
+			related[override.ProductID] = append(related[override.ProductID], override.RelatedProductID)
+		}
+	}
+	for _, pair := range computed {
+		if !skip[pair.ProductID][pair.RelatedProductID] {
+			related[pair.ProductID] = append(related[pair.ProductID], pair.RelatedProductID)
+		}
+	}
+
+	return related, nil
+}
+
+func (r *RecommendationService) hiddenIDs(productID uuid.UUID) ([]uuid.UUID, error) {
+	var ids []uuid.UUID
+	if err := config.DB.Model(&models.RelatedProductOverride{}).
+		Where("product_id = ? AND type = ?", productID, models.RelatedOverrideHidden).
+		Pluck("related_product_id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch overrides: %w", err)
+	}
+	return ids, nil
+}
+
+// activeProducts loads the active products among ids, keeping their order
+func (r *RecommendationService) activeProducts(ids []uuid.UUID, limit int) ([]models.Product, error) {
+	if len(ids) == 0 {
+		return []models.Product{}, nil
+	}
+
+	var products []models.Product
+	if err := config.DB.Where("id IN ? AND is_active = ?", ids, true).Find(&products).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch related products: %w", err)
+	}
+
+	byID := make(map[uuid.UUID]models.Product, len(products))
+	for _, product := range products {
+		byID[product.ID] = product
+	}
+
+	ordered := make([]models.Product, 0, limit)
+	for _, id := range ids {
+		if product, ok := byID[id]; ok {
+			ordered = append(ordered, product)
+			if len(ordered) == limit {
+				break
+			}
+		}
+	}
+
+	return ordered, nil
+}
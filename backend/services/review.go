@@ -0,0 +1,460 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"mime/multipart"
+	"strings"
+	"time"
+
+	"backend/config"
+	"backend/models"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+)
+
+const (
+	maxReviewPhotos      = 5
+	maxReviewTitleLength = 150
+	maxReviewBodyLength  = 5000
+)
+
+var (
+	ErrReviewNotFound   = errors.New("review not found")
+	ErrReviewNotAllowed = errors.New("only customers who received the product can review it")
+	ErrAlreadyReviewed  = errors.New("product already reviewed")
+	ErrInvalidReview    = errors.New("invalid review")
+	ErrTooManyPhotos    = errors.New("too many review photos")
+)
+
+// ReviewService manages product reviews and keeps product ratings in step
+// with the reviews moderators approve
+type ReviewService struct {
+	uploads *CloudinaryService
+}
+
+// ReviewContent is the part of a review its author writes
+type ReviewContent struct {
+	Rating int    `json:"rating"`
+	Title  string `json:"title"`
+	Body   string `json:"body"`
+}
+
+// PublicReview is an approved review as shown to shoppers, credited to the
+// reviewer's first name and last initial
+type PublicReview struct {
+	ID               uuid.UUID           `json:"id"`
+	Rating           int                 `json:"rating"`
+	Title            string              `json:"title"`
+	Body             string              `json:"body"`
+	Photos           models.ReviewPhotos `json:"photos"`
+	ReviewerName     string              `json:"reviewer_name"`
+	VerifiedPurchase bool                `json:"verified_purchase"`
+	CreatedAt        time.Time           `json:"created_at"`
+}
+
+// ModerationReview is a review as shown in the moderation queue
+type ModerationReview struct {
+	models.Review
+	ReviewerName  string `json:"reviewer_name"`
+	ReviewerEmail string `json:"reviewer_email"`
+}
+
+func NewReviewService() *ReviewService {
+	return &ReviewService{
+		uploads: NewCloudinaryService(),
+	}
+}
+
+// Create records a pending review after checking the user has a delivered
+// order containing the product
+func (s *ReviewService) Create(userID, productID uuid.UUID, content ReviewContent) (*models.Review, error) {
+	if err := s.validate(&content); err != nil {
+		return nil, err
+	}
+
+	var orderItem models.OrderItem
+	if err := config.DB.Joins("JOIN orders ON orders.id = order_items.order_id").
+		Where("orders.user_id = ? AND orders.status = ? AND order_items.product_id = ?",
+			userID, models.OrderStatusDelivered, productID).
+		Order("orders.created_at DESC").
+		First(&orderItem).Error; err != nil {
+		return nil, ErrReviewNotAllowed
+	}
+
+	var existing int64
+	if err := config.DB.Model(&models.Review{}).
+		Where("user_id = ? AND product_id = ?", userID, productID).
+		Count(&existing).Error; err != nil {
+		return nil, fmt.Errorf("failed to check existing reviews: %w", err)
+	}
+	if existing > 0 {
+		return nil, ErrAlreadyReviewed
+	}
+
+	review := models.Review{
+		ProductID:   productID,
+		UserID:      userID,
+		OrderItemID: orderItem.ID,
+		Rating:      content.Rating,
+		Title:       content.Title,
+		Body:        content.Body,
+		Status:      models.ReviewStatusPending,
+	}
+
+	if err := config.DB.Create(&review).Error; err != nil {
+		return nil, fmt.Errorf("failed to create review: %w", err)
+	}
+
+	return &review, nil
+}
+
+// GetUserReview returns one of the user's own reviews
+func (s *ReviewService) GetUserReview(userID, reviewID string) (*models.Review, error) {
+	var review models.Review
+	if err := config.DB.Where("id = ? AND user_id = ?", reviewID, userID).First(&review).Error; err != nil {
+		return nil, ErrReviewNotFound
+	}
+
+	return &review, nil
+}
+
+// GetUserReviews lists the user's reviews in every status, newest first
+func (s *ReviewService) GetUserReviews(userID string) ([]models.Review, error) {
+	var reviews []models.Review
+	if err := config.DB.Preload("Product").
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Find(&reviews).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch reviews: %w", err)
+	}
+
+	return reviews, nil
+}
+
+// Update replaces a review's content and sends it back for moderation
+func (s *ReviewService) Update(review *models.Review, content ReviewContent) error {
+	if err := s.validate(&content); err != nil {
+		return err
+	}
+
+	return s.resubmit(review, map[string]interface{}{
+		"rating": content.Rating,
+		"title":  content.Title,
+		"body":   content.Body,
+	})
+}
+
+// AddPhoto uploads a photo for a review and sends it back for moderation
+func (s *ReviewService) AddPhoto(review *models.Review, file multipart.File, filename string) (*models.ReviewPhoto, error) {
+	if len(review.Photos) >= maxReviewPhotos {
+		return nil, fmt.Errorf("%w: a review can have at most %d photos", ErrTooManyPhotos, maxReviewPhotos)
+	}
+
+	uploaded, err := s.uploads.UploadFile(file, filename)
+	if errors.Is(err, ErrInvalidFile) {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidReview, err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to upload photo: %w", err)
+	}
+
+	photo := models.ReviewPhoto{PublicID: uploaded.PublicID, URL: uploaded.SecureURL}
+	photos := append(append(models.ReviewPhotos{}, review.Photos...), photo)
+
+	if err := s.resubmit(review, map[string]interface{}{"photos": photos}); err != nil {
+		s.deletePhotos([]string{photo.PublicID})
+		return nil, err
+	}
+
+	return &photo, nil
+}
+
+// RemovePhoto removes a photo from a review and from Cloudinary
+func (s *ReviewService) RemovePhoto(review *models.Review, publicID string) error {
+	photos := models.ReviewPhotos{}
+	for _, photo := range review.Photos {
+		if photo.PublicID != publicID {
+			photos = append(photos, photo)
+		}
+	}
+	if len(photos) == len(review.Photos) {
+		return fmt.Errorf("%w: photo not found", ErrReviewNotFound)
+	}
+
+	if err := config.DB.Model(review).Update("photos", photos).Error; err != nil {
+		return fmt.Errorf("failed to remove photo: %w", err)
+	}
+	review.Photos = photos
+
+	go s.deletePhotos([]string{publicID})
+	return nil
+}
+
+// Delete removes a review, its photos and its share of the product rating
+func (s *ReviewService) Delete(review *models.Review) error {
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(review).Error; err != nil {
+			return fmt.Errorf("failed to delete review: %w", err)
+		}
+
+		if review.Status == models.ReviewStatusApproved {
+			return s.refreshRating(tx, review.ProductID)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	go s.deletePhotos(review.Photos.PublicIDs())
+	return nil
+}
+
+// DeleteUserReviews removes every review by a user inside the caller's
+// transaction and returns the Cloudinary IDs of their photos, which the
+// caller deletes once the transaction commits
+func (s *ReviewService) DeleteUserReviews(tx *gorm.DB, userID uuid.UUID) ([]string, error) {
+	var reviews []models.Review
+	if err := tx.Where("user_id = ?", userID).Find(&reviews).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch reviews: %w", err)
+	}
+
+	if len(reviews) == 0 {
+		return nil, nil
+	}
+
+	if err := tx.Where("user_id = ?", userID).Delete(&models.Review{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to delete reviews: %w", err)
+	}
+
+	var photoIDs []string
+	for _, review := range reviews {
+		photoIDs = append(photoIDs, review.Photos.PublicIDs()...)
+		if review.Status == models.ReviewStatusApproved {
+			if err := s.refreshRating(tx, review.ProductID); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return photoIDs, nil
+}
+
+// Moderate approves or rejects a review and updates the product rating
+func (s *ReviewService) Moderate(reviewID string, status models.ReviewStatus, note string, moderatorID *uuid.UUID) (*models.Review, error) {
+	if status != models.ReviewStatusApproved && status != models.ReviewStatusRejected {
+		return nil, fmt.Errorf("%w: status must be approved or rejected", ErrInvalidReview)
+	}
+
+	var review models.Review
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("id = ?", reviewID).First(&review).Error; err != nil {
+			return ErrReviewNotFound
+		}
+
+		wasApproved := review.Status == models.ReviewStatusApproved
+		now := time.Now()
+
+		if err := tx.Model(&review).Updates(map[string]interface{}{
+			"status":          status,
+			"moderation_note": strings.TrimSpace(note),
+			"moderated_by_id": moderatorID,
+			"moderated_at":    now,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to moderate review: %w", err)
+		}
+
+		if wasApproved || status == models.ReviewStatusApproved {
+			return s.refreshRating(tx, review.ProductID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &review, nil
+}
+
+// ModerationQueue lists reviews in a status, oldest first so the queue is
+// worked in the order reviews arrived
+func (s *ReviewService) ModerationQueue(status models.ReviewStatus, limit, offset int) ([]ModerationReview, int64, error) {
+	query := config.DB.Model(&models.Review{}).Where("status = ?", status)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count reviews: %w", err)
+	}
+
+	var reviews []models.Review
+	if err := query.Preload("Product").Preload("User").
+		Order("created_at ASC").
+		Offset(offset).Limit(limit).
+		Find(&reviews).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch reviews: %w", err)
+	}
+
+	queue := make([]ModerationReview, 0, len(reviews))
+	for _, review := range reviews {
+		item := ModerationReview{Review: review}
+		if review.User != nil {
+			item.ReviewerName = review.User.FullName
+			item.ReviewerEmail = review.User.Email
+		}
+		queue = append(queue, item)
+	}
+
+	return queue, total, nil
+}
+
+// ProductReviews lists a product's approved reviews. Sort is newest,
+// highest or lowest.
+func (s *ReviewService) ProductReviews(productID uuid.UUID, sort string, limit, offset int) ([]PublicReview, int64, error) {
+	query := config.DB.Model(&models.Review{}).
+		Where("product_id = ? AND status = ?", productID, models.ReviewStatusApproved)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count reviews: %w", err)
+	}
+
+	order := "created_at DESC"
+	switch sort {
+	case "highest":
+		order = "rating DESC, created_at DESC"
+	case "lowest":
+		order = "rating ASC, created_at DESC"
+	}
+
+	var reviews []models.Review
+	if err := query.Preload("User").
+		Order(order).
+		Offset(offset).Limit(limit).
+		Find(&reviews).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to fetch reviews: %w", err)
+	}
+
+	public := make([]PublicReview, 0, len(reviews))
+	for _, review := range reviews {
+		item := PublicReview{
+			ID:               review.ID,
+			Rating:           review.Rating,
+			Title:            review.Title,
+			Body:             review.Body,
+			Photos:           review.Photos,
+			ReviewerName:     "Customer",
+			VerifiedPurchase: true,
+			CreatedAt:        review.CreatedAt,
+		}
+		if review.User != nil {
+			item.ReviewerName = reviewerName(review.User.FullName)
+		}
+		public = append(public, item)
+	}
+
+	return public, total, nil
+}
+
+// RatingBreakdown counts a product's approved reviews per star rating
+func (s *ReviewService) RatingBreakdown(productID uuid.UUID) (map[int]int64, error) {
+	var rows []struct {
+		Rating int
+		Count  int64
+	}
+	if err := config.DB.Model(&models.Review{}).
+		Select("rating, COUNT(*) AS count").
+		Where("product_id = ? AND status = ?", productID, models.ReviewStatusApproved).
+		Group("rating").
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to count ratings: %w", err)
+	}
+
+	breakdown := make(map[int]int64, models.MaxReviewRating)
+	for rating := models.MinReviewRating; rating <= models.MaxReviewRating; rating++ {
+		breakdown[rating] = 0
+	}
+	for _, row := range rows {
+		breakdown[row.Rating] = row.Count
+	}
+
+	return breakdown, nil
+}
+
+// resubmit applies an author's change and returns the review to the queue
+func (s *ReviewService) resubmit(review *models.Review, updates map[string]interface{}) error {
+	wasApproved := review.Status == models.ReviewStatusApproved
+	updates["status"] = models.ReviewStatusPending
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(review).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update review: %w", err)
+		}
+
+		if wasApproved {
+			return s.refreshRating(tx, review.ProductID)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return config.DB.Where("id = ?", review.ID).First(review).Error
+}
+
+// refreshRating recomputes a product's rating from its approved reviews
+func (s *ReviewService) refreshRating(tx *gorm.DB, productID uuid.UUID) error {
+	if err := tx.Exec(`
+		UPDATE products SET
+			rating_average = COALESCE((SELECT ROUND(AVG(rating), 2) FROM reviews WHERE product_id = @id AND status = @status), 0),
+			rating_count = (SELECT COUNT(*) FROM reviews WHERE product_id = @id AND status = @status)
+		WHERE id = @id`,
+		map[string]interface{}{"id": productID, "status": models.ReviewStatusApproved},
+	).Error; err != nil {
+		return fmt.Errorf("failed to update product rating: %w", err)
+	}
+
+	return nil
+}
+
+func (s *ReviewService) validate(content *ReviewContent) error {
+	content.Title = strings.TrimSpace(content.Title)
+	content.Body = strings.TrimSpace(content.Body)
+
+	switch {
+	case content.Rating < models.MinReviewRating || content.Rating > models.MaxReviewRating:
+		return fmt.Errorf("%w: rating must be between %d and %d", ErrInvalidReview, models.MinReviewRating, models.MaxReviewRating)
+	case len(content.Title) > maxReviewTitleLength:
+		return fmt.Errorf("%w: title must be at most %d characters", ErrInvalidReview, maxReviewTitleLength)
+	case len(content.Body) > maxReviewBodyLength:
+		return fmt.Errorf("%w: review must be at most %d characters", ErrInvalidReview, maxReviewBodyLength)
+	}
+
+	return nil
+}
+
+// deletePhotos removes review photos from Cloudinary, logging failures
+// since the review itself is already gone
+func (s *ReviewService) deletePhotos(publicIDs []string) {
+	for _, publicID := range publicIDs {
+		if err := s.uploads.DeleteFile(publicID); err != nil {
+			fmt.Printf("Failed to delete review photo %s: %v\n", publicID, err)
+		}
+	}
+}
+
+// reviewerName shortens a full name to first name and last initial
+func reviewerName(fullName string) string {
+	parts := strings.Fields(fullName)
+	switch len(parts) {
+	case 0:
+		return "Customer"
+	case 1:
+		return parts[0]
+	default:
+		last := []rune(parts[len(parts)-1])
+		return parts[0] + " " + string(last[0]) + "."
+	}
+}
@@ -0,0 +1,171 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"backend/config"
+	"backend/models"
+)
+
+var (
+	ErrRoleNotFound      = errors.New("role not found")
+	ErrRoleExists        = errors.New("role already exists")
+	ErrRoleInUse         = errors.New("role is assigned to users")
+	ErrSystemRole        = errors.New("system roles cannot be modified")
+	ErrUnknownPermission = errors.New("unknown permission")
+	ErrInvalidRoleName   = errors.New("role names must be lowercase letters, digits and underscores")
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+type RoleService struct{}
+
+func NewRoleService() *RoleService {
+	return &RoleService{}
+}
+
+// GetRole looks up a role by name
+func (r *RoleService) GetRole(name string) (*models.Role, error) {
+	var role models.Role
+	if err := config.DB.Where("name = ?", name).First(&role).Error; err != nil {
+		return nil, ErrRoleNotFound
+	}
+	return &role, nil
+}
+
+// HasAnyPermission reports whether the named role grants at least one of the permissions
+func (r *RoleService) HasAnyPermission(roleName string, permissions ...models.Permission) bool {
+	role, err := r.GetRole(roleName)
+	if err != nil {
+		return false
+	}
+
+	for _, permission := range permissions {
+		if role.HasPermission(permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// ListRoles returns every role with the number of users assigned to it
+func (r *RoleService) ListRoles() ([]models.Role, map[models.UserRole]int64, error) {
+	var roles []models.Role
+	if err := config.DB.Order("is_system DESC, name ASC").Find(&roles).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch roles: %w", err)
+	}
+
+	var counts []struct {
+		Role  models.UserRole
+		Count int64
+	}
+	if err := config.DB.Model(&models.User{}).
+		Select("role, COUNT(*) as count").
+		Group("role").
+		Scan(&counts).Error; err != nil {
+		return nil, nil, fmt.Errorf("failed to count role members: %w", err)
+	}
+
+	members := make(map[models.UserRole]int64, len(counts))
+	for _, count := range counts {
+		members[count.Role] = count.Count
+	}
+
+	return roles, members, nil
+}
+
+// CreateRole adds a custom role
+func (r *RoleService) CreateRole(name, description string, permissions []models.Permission) (*models.Role, error) {
+	if !roleNamePattern.MatchString(name) {
+		return nil, ErrInvalidRoleName
+	}
+
+	if err := validatePermissions(permissions); err != nil {
+		return nil, err
+	}
+
+	if _, err := r.GetRole(name); err == nil {
+		return nil, ErrRoleExists
+	}
+
+	role := models.Role{
+		Name:        models.UserRole(name),
+		Description: description,
+		Permissions: permissions,
+	}
+
+	if err := config.DB.Create(&role).Error; err != nil {
+		return nil, fmt.Errorf("failed to create role: %w", err)
+	}
+
+	return &role, nil
+}
+
+// UpdateRole changes a role's description and permissions. The admin role
+// always keeps every permission so the system cannot be locked out.
+func (r *RoleService) UpdateRole(id string, description *string, permissions []models.Permission) (*models.Role, error) {
+	var role models.Role
+	if err := config.DB.First(&role, "id = ?", id).Error; err != nil {
+		return nil, ErrRoleNotFound
+	}
+
+	updates := map[string]interface{}{}
+	if description != nil {
+		updates["description"] = *description
+	}
+	if permissions != nil {
+		if role.Name == models.RoleAdmin {
+			return nil, ErrSystemRole
+		}
+		if err := validatePermissions(permissions); err != nil {
+			return nil, err
+		}
+		updates["permissions"] = models.PermissionList(permissions)
+	}
+
+	if len(updates) > 0 {
+		if err := config.DB.Model(&role).Updates(updates).Error; err != nil {
+			return nil, fmt.Errorf("failed to update role: %w", err)
+		}
+	}
+
+	return &role, nil
+}
+
+// DeleteRole removes a custom role that no user is assigned to
+func (r *RoleService) DeleteRole(id string) error {
+	var role models.Role
+	if err := config.DB.First(&role, "id = ?", id).Error; err != nil {
+		return ErrRoleNotFound
+	}
+
+	if role.IsSystem {
+		return ErrSystemRole
+	}
+
+	var members int64
+	if err := config.DB.Model(&models.User{}).Where("role = ?", role.Name).Count(&members).Error; err != nil {
+		return fmt.Errorf("failed to count role members: %w", err)
+	}
+	if members > 0 {
+		return ErrRoleInUse
+	}
+
+	if err := config.DB.Delete(&role).Error; err != nil {
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+
+	return nil
+}
+
+// validatePermissions rejects permissions that no route checks for
+func validatePermissions(permissions []models.Permission) error {
+	for _, permission := range permissions {
+		if !models.IsValidPermission(permission) {
+			return fmt.Errorf("%w: %s", ErrUnknownPermission, permission)
+		}
+	}
+	return nil
+}
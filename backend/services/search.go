@@ -0,0 +1,118 @@
+package services
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+
+	"backend/config"
+	"backend/models"
+
+	uuid "github.com/satori/go.uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Markers ts_headline wraps matches in; they are swapped for <mark> tags
+// after the surrounding text has been HTML-escaped
+const (
+	highlightStart = "[[hl]]"
+	highlightStop  = "[[/hl]]"
+)
+
+// SearchService ranks products against a search term with PostgreSQL full-text
+// search, falling back to trigram similarity when the term matches nothing
+type SearchService struct{}
+
+// SearchHighlight holds HTML snippets with matched words wrapped in <mark>
+type SearchHighlight struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+}
+
+func NewSearchService() *SearchService {
+	return &SearchService{}
+}
+
+// Match restricts a product query to products matching the term. With fuzzy
+// set, misspelt terms such as "hamer" match similar names and SKUs instead.
+func (s *SearchService) Match(db *gorm.DB, term string, fuzzy bool) *gorm.DB {
+	if fuzzy {
+		return db.Where("? <% products.name OR ? <% products.sku", term, term)
+	}
+	return db.Where("products.search_vector @@ to_tsquery('english', ?)", toTSQuery(term))
+}
+
+// OrderByRelevance sorts the best matches for the term first
+func (s *SearchService) OrderByRelevance(db *gorm.DB, term string, fuzzy bool) *gorm.DB {
+	if fuzzy {
+		return db.Order(clause.OrderBy{Expression: clause.Expr{
+			SQL:                "GREATEST(word_similarity(?, products.name), word_similarity(?, products.sku)) DESC",
+			Vars:               []interface{}{term, term},
+			WithoutParentheses: true,
+		}})
+	}
+
+	return db.Order(clause.OrderBy{Expression: clause.Expr{
+		SQL:                "ts_rank_cd(products.search_vector, to_tsquery('english', ?)) DESC",
+		Vars:               []interface{}{toTSQuery(term)},
+		WithoutParentheses: true,
+	}})
+}
+
+// Highlights returns snippets of each product's name and description with
+// the words matching the term marked
+func (s *SearchService) Highlights(term string, productIDs []uuid.UUID) (map[uuid.UUID]SearchHighlight, error) {
+	highlights := make(map[uuid.UUID]SearchHighlight, len(productIDs))
+	if len(productIDs) == 0 {
+		return highlights, nil
+	}
+
+	options := fmt.Sprintf(`StartSel="%s", StopSel="%s", MaxWords=25, MinWords=10, MaxFragments=2`, highlightStart, highlightStop)
+
+	var rows []struct {
+		ID          uuid.UUID
+		Name        string
+		Description string
+	}
+	if err := config.DB.Model(&models.Product{}).
+		Select(`products.id,
+			ts_headline('english', products.name, to_tsquery('english', ?), ?) AS name,
+			ts_headline('english', products.description, to_tsquery('english', ?), ?) AS description`,
+			toTSQuery(term), options, toTSQuery(term), options).
+		Where("products.id IN ?", productIDs).
+		Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to highlight search results: %w", err)
+	}
+
+	for _, row := range rows {
+		highlights[row.ID] = SearchHighlight{
+			Name:        markHighlights(row.Name),
+			Description: markHighlights(row.Description),
+		}
+	}
+
+	return highlights, nil
+}
+
+// toTSQuery turns free text into a tsquery requiring every word, with the
+// last word matched as a prefix so results appear while the user types
+func toTSQuery(term string) string {
+	words := strings.FieldsFunc(term, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	if len(words) == 0 {
+		return ""
+	}
+
+	words[len(words)-1] += ":*"
+	return strings.Join(words, " & ")
+}
+
+func markHighlights(snippet string) string {
+	snippet = html.EscapeString(snippet)
+	snippet = strings.ReplaceAll(snippet, highlightStart, "<mark>")
+	return strings.ReplaceAll(snippet, highlightStop, "</mark>")
+}
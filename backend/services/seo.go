@@ -0,0 +1,301 @@
+package services
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"backend/config"
+	"backend/models"
+)
+
+// sitemapPageSize is the most URLs the sitemap protocol allows in one file
+const sitemapPageSize = 50000
+
+const (
+	schemaInStock    = "https://schema.org/InStock"
+	schemaOutOfStock = "https://schema.org/OutOfStock"
+	schemaNew        = "https://schema.org/NewCondition"
+)
+
+// ErrSitemapURLMissing means a public URL needed for sitemap links is not
+// configured. Sitemaps only allow absolute URLs, so none is served.
+var ErrSitemapURLMissing = errors.New("public URL not configured")
+
+// unitCodes maps units of measure to UN/CEFACT codes used by schema.org
+var unitCodes = map[models.UnitOfMeasure]string{
+	models.UnitMetre:    "MTR",
+	models.UnitKilogram: "KGM",
+	models.UnitLitre:    "LTR",
+}
+
+// SEOService builds sitemaps and schema.org structured data that link to
+// the storefront, whose product, category and brand pages live at
+// /products/<slug>, /categories/<slug> and /brands/<slug>
+type SEOService struct {
+	storefrontURL string
+	apiURL        string
+	currency      string
+}
+
+type SitemapURL struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod,omitempty"`
+}
+
+type URLSet struct {
+	XMLName xml.Name     `xml:"urlset"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	URLs    []SitemapURL `xml:"url"`
+}
+
+type SitemapIndex struct {
+	XMLName  xml.Name     `xml:"sitemapindex"`
+	XMLNS    string       `xml:"xmlns,attr"`
+	Sitemaps []SitemapURL `xml:"sitemap"`
+}
+
+// ProductJSONLD is a schema.org Product
+type ProductJSONLD struct {
+	Context         string           `json:"@context"`
+	Type            string           `json:"@type"`
+	Name            string           `json:"name"`
+	SKU             string           `json:"sku"`
+	Description     string           `json:"description,omitempty"`
+	Image           []string         `json:"image,omitempty"`
+	URL             string           `json:"url"`
+	Category        string           `json:"category,omitempty"`
+	Brand           *BrandJSONLD     `json:"brand,omitempty"`
+	AggregateRating *AggregateRating `json:"aggregateRating,omitempty"`
+	Offers          []OfferJSONLD    `json:"offers"`
+}
+
+type BrandJSONLD struct {
+	Type string `json:"@type"`
+	Name string `json:"name"`
+}
+
+type AggregateRating struct {
+	Type        string  `json:"@type"`
+	RatingValue float64 `json:"ratingValue"`
+	ReviewCount int     `json:"reviewCount"`
+}
+
+// OfferJSONLD is a schema.org Offer for the product or one of its variants
+type OfferJSONLD struct {
+	Type               string                    `json:"@type"`
+	SKU                string                    `json:"sku"`
+	Name               string                    `json:"name,omitempty"`
+	Price              string                    `json:"price"`
+	PriceCurrency      string                    `json:"priceCurrency"`
+	PriceValidUntil    string                    `json:"priceValidUntil,omitempty"`
+	Availability       string                    `json:"availability"`
+	ItemCondition      string                    `json:"itemCondition"`
+	URL                string                    `json:"url"`
+	PriceSpecification *UnitPriceSpecificationLD `json:"priceSpecification,omitempty"`
+}
+
+// UnitPriceSpecificationLD states that the price is per metre, kilogram or litre
+type UnitPriceSpecificationLD struct {
+	Type              string            `json:"@type"`
+	Price             string            `json:"price"`
+	PriceCurrency     string            `json:"priceCurrency"`
+	ReferenceQuantity QuantitativeValue `json:"referenceQuantity"`
+}
+
+type QuantitativeValue struct {
+	Type     string  `json:"@type"`
+	Value    float64 `json:"value"`
+	UnitCode string  `json:"unitCode"`
+}
+
+func NewSEOService() *SEOService {
+	currency := os.Getenv("STORE_CURRENCY")
+	if currency == "" {
+		currency = "NGN"
+	}
+
+	return &SEOService{
+		storefrontURL: strings.TrimRight(os.Getenv("FRONTEND_URL"), "/"),
+		apiURL:        strings.TrimRight(os.Getenv("BASE_URL"), "/"),
+		currency:      currency,
+	}
+}
+
+// SitemapPages returns how many sitemap files the catalog needs
+func (s *SEOService) SitemapPages() (int, error) {
+	var categories, brands, products int64
+	if err := config.DB.Model(&models.Category{}).Count(&categories).Error; err != nil {
+		return 0, fmt.Errorf("failed to count categories: %w", err)
+	}
+	if err := config.DB.Model(&models.Brand{}).Where("is_active = ?", true).Count(&brands).Error; err != nil {
+		return 0, fmt.Errorf("failed to count brands: %w", err)
+	}
+	if err := config.DB.Model(&models.Product{}).Where("is_active = ?", true).Count(&products).Error; err != nil {
+		return 0, fmt.Errorf("failed to count products: %w", err)
+	}
+
+	// The storefront home page is listed too
+	total := int(categories+brands+products) + 1
+	return (total + sitemapPageSize - 1) / sitemapPageSize, nil
+}
+
+// SitemapIndex lists the sitemap files of a catalog too large for one at
+// the public URL of the API, which may differ from the host the request
+// reached behind a proxy
+func (s *SEOService) SitemapIndex(pages int) (*SitemapIndex, error) {
+	if s.apiURL == "" {
+		return nil, fmt.Errorf("%w: BASE_URL is not set", ErrSitemapURLMissing)
+	}
+
+	index := &SitemapIndex{XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	for page := 1; page <= pages; page++ {
+		index.Sitemaps = append(index.Sitemaps, SitemapURL{
+			Loc: fmt.Sprintf("%s/sitemap-%d.xml", s.apiURL, page),
+		})
+	}
+	return index, nil
+}
+
+// SitemapPage returns one page of storefront URLs: the home page, then
+// categories, then active brands, then active products, each in slug order
+func (s *SEOService) SitemapPage(page int) (*URLSet, error) {
+	if s.storefrontURL == "" {
+		return nil, fmt.Errorf("%w: FRONTEND_URL is not set", ErrSitemapURLMissing)
+	}
+
+	var entries []struct {
+		Kind      string
+		Slug      string
+		UpdatedAt time.Time
+	}
+
+	if err := config.DB.Raw(`
+		SELECT kind, slug, updated_at FROM (
+			SELECT 1 AS sort, 'category' AS kind, slug, updated_at FROM categories
+			UNION ALL
+			SELECT 2 AS sort, 'brand' AS kind, slug, updated_at FROM brands WHERE is_active = TRUE
+			UNION ALL
+			SELECT 3 AS sort, 'product' AS kind, slug, updated_at FROM products WHERE is_active = TRUE
+		) entries
+		ORDER BY sort, slug
+		LIMIT ? OFFSET ?`,
+		sitemapPageSize, s.sitemapOffset(page),
+	).Scan(&entries).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch sitemap entries: %w", err)
+	}
+
+	set := &URLSet{XMLNS: "http://www.sitemaps.org/schemas/sitemap/0.9"}
+	if page == 1 {
+		set.URLs = append(set.URLs, SitemapURL{Loc: s.storefrontURL + "/"})
+	}
+
+	for _, entry := range entries {
+		path := "/products/"
+		switch entry.Kind {
+		case "category":
+			path = "/categories/"
+		case "brand":
+			path = "/brands/"
+		}
+		set.URLs = append(set.URLs, SitemapURL{
+			Loc:     s.storefrontURL + path + entry.Slug,
+			LastMod: entry.UpdatedAt.UTC().Format("2006-01-02"),
+		})
+	}
+
+	return set, nil
+}
+
+// sitemapOffset skips the entries on earlier pages, allowing for the home
+// page taking a slot on the first one
+func (s *SEOService) sitemapOffset(page int) int {
+	if page <= 1 {
+		return 0
+	}
+	return (page-1)*sitemapPageSize - 1
+}
+
+// ProductJSONLD describes a product as schema.org structured data. Products
+// with variants get one offer per active variant.
+func (s *SEOService) ProductJSONLD(product *models.Product) *ProductJSONLD {
+	url := s.storefrontURL + "/products/" + product.Slug
+
+	data := &ProductJSONLD{
+		Context:     "https://schema.org",
+		Type:        "Product",
+		Name:        product.Name,
+		SKU:         product.SKU,
+		Description: product.Description,
+		Image:       product.ImagesJSON,
+		URL:         url,
+		Category:    product.Category.Name,
+		Offers:      []OfferJSONLD{},
+	}
+
+	if product.Brand != nil {
+		data.Brand = &BrandJSONLD{Type: "Brand", Name: product.Brand.Name}
+	}
+
+	if product.RatingCount > 0 {
+		data.AggregateRating = &AggregateRating{
+			Type:        "AggregateRating",
+			RatingValue: product.RatingAverage,
+			ReviewCount: product.RatingCount,
+		}
+	}
+
+	if len(product.Variants) == 0 {
+		data.Offers = append(data.Offers, s.offer(product, product.SKU, "", product.Price, product.SalePricing, product.StockQuantity, url))
+		return data
+	}
+
+	for _, variant := range product.Variants {
+		data.Offers = append(data.Offers, s.offer(product, variant.SKU, variant.Name, variant.Price, variant.SalePricing, variant.StockQuantity, url))
+	}
+
+	return data
+}
+
+func (s *SEOService) offer(product *models.Product, sku, name string, listPrice float64, sale models.SalePricing, stock float64, url string) OfferJSONLD {
+	now := time.Now()
+	price := sale.PriceAt(listPrice, now)
+
+	offer := OfferJSONLD{
+		Type:          "Offer",
+		SKU:           sku,
+		Name:          name,
+		Price:         fmt.Sprintf("%.2f", price),
+		PriceCurrency: s.currency,
+		Availability:  schemaOutOfStock,
+		ItemCondition: schemaNew,
+		URL:           url,
+	}
+
+	if stock > 0 {
+		offer.Availability = schemaInStock
+	}
+
+	// A sale price is only valid until the sale ends
+	if sale.SaleActive(listPrice, now) && sale.SaleEndsAt != nil {
+		offer.PriceValidUntil = sale.SaleEndsAt.UTC().Format("2006-01-02")
+	}
+
+	if code, ok := unitCodes[product.Unit]; ok {
+		offer.PriceSpecification = &UnitPriceSpecificationLD{
+			Type:          "UnitPriceSpecification",
+			Price:         offer.Price,
+			PriceCurrency: s.currency,
+			ReferenceQuantity: QuantitativeValue{
+				Type:     "QuantitativeValue",
+				Value:    1,
+				UnitCode: code,
+			},
+		}
+	}
+
+	return offer
+}
@@ -0,0 +1,255 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"backend/config"
+	"backend/models"
+	"backend/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrInvalidSession      = errors.New("session not found or no longer active")
+)
+
+// sessionTouchInterval limits how often last-seen timestamps are written
+const sessionTouchInterval = time.Minute
+
+type SessionService struct{}
+
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+	SessionID    string `json:"session_id"`
+}
+
+func NewSessionService() *SessionService {
+	return &SessionService{}
+}
+
+// CreateSession starts a new session for the user and issues the first token pair
+func (s *SessionService) CreateSession(user *models.User, userAgent, ipAddress string, twoFactorVerified bool) (*TokenPair, error) {
+	var pair *TokenPair
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		session := models.Session{
+			UserID:            user.ID,
+			UserAgent:         userAgent,
+			IPAddress:         ipAddress,
+			LastSeenAt:        time.Now(),
+			TwoFactorVerified: twoFactorVerified,
+			ExpiresAt:         time.Now().Add(utils.RefreshTokenExpiry()),
+		}
+
+		if err := tx.Create(&session).Error; err != nil {
+			return fmt.Errorf("failed to create session: %w", err)
+		}
+
+		var err error
+		pair, err = s.issueTokens(tx, &session, user)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return pair, nil
+}
+
+// Refresh rotates a refresh token. Presenting a token that was already used
+// revokes the whole session, since only a copied token can be replayed.
+func (s *SessionService) Refresh(rawToken, userAgent, ipAddress string) (*TokenPair, *models.User, error) {
+	var pair *TokenPair
+	var user models.User
+
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var token models.RefreshToken
+		if err := tx.Preload("Session").Where("token_hash = ?", utils.HashToken(rawToken)).First(&token).Error; err != nil {
+			return ErrInvalidRefreshToken
+		}
+
+		session := token.Session
+		if !session.IsActive() {
+			return ErrInvalidRefreshToken
+		}
+
+		if token.UsedAt != nil {
+			return ErrRefreshTokenReused
+		}
+
+		if time.Now().After(token.ExpiresAt) {
+			return ErrInvalidRefreshToken
+		}
+
+		// Mark the token as used; losing this race means a concurrent reuse
+		result := tx.Model(&models.RefreshToken{}).
+			Where("id = ? AND used_at IS NULL", token.ID).
+			Update("used_at", time.Now())
+		if result.Error != nil {
+			return fmt.Errorf("failed to rotate refresh token: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenReused
+		}
+
+		if err := tx.First(&user, "id = ?", session.UserID).Error; err != nil {
+			return ErrInvalidRefreshToken
+		}
+
+		if !user.IsActive {
+			return ErrInvalidRefreshToken
+		}
+
+		// Slide the session expiry forward with each rotation
+		session.ExpiresAt = time.Now().Add(utils.RefreshTokenExpiry())
+		if err := tx.Model(&session).Updates(map[string]interface{}{
+			"expires_at":   session.ExpiresAt,
+			"last_seen_at": time.Now(),
+			"user_agent":   userAgent,
+			"ip_address":   ipAddress,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to extend session: %w", err)
+		}
+
+		var err error
+		pair, err = s.issueTokens(tx, &session, &user)
+		return err
+	})
+
+	if errors.Is(err, ErrRefreshTokenReused) {
+		// Revoke outside the rolled-back transaction so it sticks
+		var token models.RefreshToken
+		if config.DB.Where("token_hash = ?", utils.HashToken(rawToken)).First(&token).Error == nil {
+			if revokeErr := s.RevokeSession(token.SessionID.String(), models.SessionRevokedTokenReuse); revokeErr != nil {
+				fmt.Printf("Failed to revoke session after refresh token reuse: %v\n", revokeErr)
+			}
+		}
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return pair, &user, nil
+}
+
+// RevokeSession revokes a single session and all of its refresh tokens
+func (s *SessionService) RevokeSession(sessionID string, reason models.SessionRevokeReason) error {
+	return config.DB.Model(&models.Session{}).
+		Where("id = ? AND revoked_at IS NULL", sessionID).
+		Updates(map[string]interface{}{
+			"revoked_at":     time.Now(),
+			"revoked_reason": reason,
+		}).Error
+}
+
+// RevokeUserSessions revokes every active session belonging to a user
+func (s *SessionService) RevokeUserSessions(userID string, reason models.SessionRevokeReason) error {
+	return config.DB.Model(&models.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Updates(map[string]interface{}{
+			"revoked_at":     time.Now(),
+			"revoked_reason": reason,
+		}).Error
+}
+
+// RevokeOtherSessions revokes every active session of a user except the given one
+func (s *SessionService) RevokeOtherSessions(userID, currentSessionID string, reason models.SessionRevokeReason) (int64, error) {
+	result := config.DB.Model(&models.Session{}).
+		Where("user_id = ? AND id != ? AND revoked_at IS NULL", userID, currentSessionID).
+		Updates(map[string]interface{}{
+			"revoked_at":     time.Now(),
+			"revoked_reason": reason,
+		})
+
+	return result.RowsAffected, result.Error
+}
+
+// MarkTwoFactorVerified records that the session's user has passed a second factor
+func (s *SessionService) MarkTwoFactorVerified(sessionID string) error {
+	return config.DB.Model(&models.Session{}).
+		Where("id = ?", sessionID).
+		Update("two_factor_verified", true).Error
+}
+
+// GetActiveSession returns a session that exists and has not been revoked or expired
+func (s *SessionService) GetActiveSession(sessionID string) (*models.Session, error) {
+	if sessionID == "" {
+		return nil, ErrInvalidSession
+	}
+
+	var session models.Session
+	if err := config.DB.Where("id = ?", sessionID).First(&session).Error; err != nil {
+		return nil, ErrInvalidSession
+	}
+
+	if !session.IsActive() {
+		return nil, ErrInvalidSession
+	}
+
+	return &session, nil
+}
+
+// TouchSession records activity on a session, at most once per sessionTouchInterval
+func (s *SessionService) TouchSession(session *models.Session, ipAddress string) {
+	if time.Since(session.LastSeenAt) < sessionTouchInterval {
+		return
+	}
+
+	if err := config.DB.Model(session).Updates(map[string]interface{}{
+		"last_seen_at": time.Now(),
+		"ip_address":   ipAddress,
+	}).Error; err != nil {
+		fmt.Printf("Failed to update session activity: %v\n", err)
+	}
+}
+
+// GetUserSessions lists the active sessions of a user, most recently used first
+func (s *SessionService) GetUserSessions(userID string) ([]models.Session, error) {
+	var sessions []models.Session
+	if err := config.DB.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
+// issueTokens creates a new refresh token for the session and a matching access token
+func (s *SessionService) issueTokens(tx *gorm.DB, session *models.Session, user *models.User) (*TokenPair, error) {
+	rawToken, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken := models.RefreshToken{
+		SessionID: session.ID,
+		TokenHash: utils.HashToken(rawToken),
+		ExpiresAt: session.ExpiresAt,
+	}
+
+	if err := tx.Create(&refreshToken).Error; err != nil {
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	accessToken, err := utils.GenerateJWT(user.ID.String(), user.Email, string(user.Role), session.ID.String())
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: rawToken,
+		ExpiresIn:    int64(utils.AccessTokenExpiry().Seconds()),
+		SessionID:    session.ID.String(),
+	}, nil
+}
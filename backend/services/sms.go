@@ -98,8 +98,8 @@ func (t *TwilioService) SendDeliveryNotificationSMS(phoneNumber, orderID string)
 }
 
 // SendLowStockAlertSMS sends low stock alert SMS to admin
-func (t *TwilioService) SendLowStockAlertSMS(adminPhone, productName, sku string, currentStock int) error {
-	message := fmt.Sprintf("LOW STOCK ALERT: %s (SKU: %s) has only %d units remaining. Please restock.", productName, sku, currentStock)
+func (t *TwilioService) SendLowStockAlertSMS(adminPhone, productName, sku string, currentStock float64, unit string) error {
+	message := fmt.Sprintf("LOW STOCK ALERT: %s (SKU: %s) has only %g %s remaining. Please restock.", productName, sku, currentStock, unit)
 	return t.SendSMS(adminPhone, message)
 }
 
@@ -115,6 +115,18 @@ func (t *TwilioService) SendPasswordResetSMS(phoneNumber, resetCode string) erro
 	return t.SendSMS(phoneNumber, message)
 }
 
+// SendVerificationCodeSMS sends a phone number verification code
+func (t *TwilioService) SendVerificationCodeSMS(phoneNumber, code string) error {
+	message := fmt.Sprintf("Your Hardware Store verification code is: %s. It expires in 10 minutes.", code)
+	return t.SendSMS(phoneNumber, message)
+}
+
+// SendLoginCodeSMS sends a one-time sign-in code
+func (t *TwilioService) SendLoginCodeSMS(phoneNumber, code string) error {
+	message := fmt.Sprintf("Your Hardware Store sign-in code is: %s. It expires in 5 minutes. Never share this code.", code)
+	return t.SendSMS(phoneNumber, message)
+}
+
 // SendServiceRequestConfirmationSMS sends service request confirmation SMS
 func (t *TwilioService) SendServiceRequestConfirmationSMS(phoneNumber, requestID, serviceType string) error {
 	message := fmt.Sprintf("Service request #%s for %s has been received. We'll contact you within 24 hours.", requestID, serviceType)
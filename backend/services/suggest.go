@@ -0,0 +1,145 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"backend/config"
+	"backend/models"
+
+	uuid "github.com/satori/go.uuid"
+)
+
+const (
+	// suggestMinLength avoids matching half the catalog on a single keystroke
+	suggestMinLength = 2
+	suggestCacheTTL  = 5 * time.Minute
+)
+
+// SuggestService returns autocomplete suggestions for the search box
+type SuggestService struct {
+	redis *RedisService
+}
+
+type ProductSuggestion struct {
+	ID    uuid.UUID `json:"id"`
+	Name  string    `json:"name"`
+	Slug  string    `json:"slug"`
+	SKU   string    `json:"sku"`
+	Price float64   `json:"price"`
+	models.SalePricing
+	models.DisplayPrices
+}
+
+type CategorySuggestion struct {
+	ID   uuid.UUID `json:"id"`
+	Name string    `json:"name"`
+	Slug string    `json:"slug"`
+}
+
+type Suggestions struct {
+	Products   []ProductSuggestion  `json:"products"`
+	Categories []CategorySuggestion `json:"categories"`
+}
+
+func NewSuggestService() *SuggestService {
+	return &SuggestService{
+		redis: SharedRedisService(),
+	}
+}
+
+// Suggest returns products whose name or SKU, and categories whose name,
+// start with the prefix or have a word starting with it. Closer matches come
+// first, then the items ordered most often.
+func (s *SuggestService) Suggest(prefix string, limit int) (*Suggestions, error) {
+	prefix = strings.ToLower(strings.Join(strings.Fields(prefix), " "))
+	if len([]rune(prefix)) < suggestMinLength {
+		return &Suggestions{Products: []ProductSuggestion{}, Categories: []CategorySuggestion{}}, nil
+	}
+
+	cacheKey := fmt.Sprintf("catalog:suggest:%d:%s", limit, prefix)
+
+	var suggestions Suggestions
+	err := s.redis.Get(cacheKey, &suggestions)
+	if err == nil {
+		s.setDisplayPrices(&suggestions)
+		return &suggestions, nil
+	}
+	// Only a plain miss is worth caching; when Redis is down, setting would
+	// fail and log on every keystroke
+	cacheable := errors.Is(err, ErrCacheMiss)
+
+	startsWith := escapeLike(prefix) + "%"
+	wordStartsWith := "% " + startsWith
+
+	suggestions.Products = []ProductSuggestion{}
+	if err := config.DB.Raw(`
+		SELECT p.id, p.name, p.slug, p.sku, p.price, p.sale_price, p.sale_starts_at, p.sale_ends_at
+		FROM products p
+		LEFT JOIN (
+			SELECT oi.product_id, SUM(oi.quantity) AS sold
+			FROM order_items oi JOIN orders o ON o.id = oi.order_id
+			WHERE o.status != ?
+			GROUP BY oi.product_id
+		) popularity ON popularity.product_id = p.id
+		WHERE p.is_active = ? AND p.deleted_at IS NULL
+			AND (p.name ILIKE ? OR p.name ILIKE ? OR p.sku ILIKE ?)
+		ORDER BY (p.name ILIKE ? OR p.sku ILIKE ?) DESC, COALESCE(popularity.sold, 0) DESC, p.name ASC
+		LIMIT ?`,
+		models.OrderStatusCancelled, true,
+		startsWith, wordStartsWith, startsWith,
+		startsWith, startsWith,
+		limit,
+	).Scan(&suggestions.Products).Error; err != nil {
+		return nil, fmt.Errorf("failed to suggest products: %w", err)
+	}
+
+	suggestions.Categories = []CategorySuggestion{}
+	if err := config.DB.Raw(`
+		SELECT c.id, c.name, c.slug
+		FROM categories c
+		LEFT JOIN (
+			SELECT p.category_id, SUM(oi.quantity) AS sold
+			FROM order_items oi
+			JOIN orders o ON o.id = oi.order_id
+			JOIN products p ON p.id = oi.product_id
+			WHERE o.status != ?
+			GROUP BY p.category_id
+		) popularity ON popularity.category_id = c.id
+		WHERE c.deleted_at IS NULL AND (c.name ILIKE ? OR c.name ILIKE ?)
+		ORDER BY (c.name ILIKE ?) DESC, COALESCE(popularity.sold, 0) DESC, c.name ASC
+		LIMIT ?`,
+		models.OrderStatusCancelled,
+		startsWith, wordStartsWith,
+		startsWith,
+		limit,
+	).Scan(&suggestions.Categories).Error; err != nil {
+		return nil, fmt.Errorf("failed to suggest categories: %w", err)
+	}
+
+	// Sales start and end while suggestions are cached, so the cache keeps the
+	// sale itself and prices are worked out on the way out
+	if cacheable {
+		if err := s.redis.Set(cacheKey, suggestions, CacheOptions{TTL: suggestCacheTTL}); err != nil {
+			fmt.Printf("Failed to cache suggestions: %v\n", err)
+		}
+	}
+
+	s.setDisplayPrices(&suggestions)
+	return &suggestions, nil
+}
+
+// escapeLike stops user input from acting as LIKE wildcards
+func escapeLike(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+}
+
+// setDisplayPrices works out what each suggested product costs now
+func (s *SuggestService) setDisplayPrices(suggestions *Suggestions) {
+	for i := range suggestions.Products {
+		product := &suggestions.Products[i]
+		product.DisplayPrices.Set(product.Price, product.SalePricing)
+	}
+}
@@ -0,0 +1,207 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"backend/config"
+	"backend/models"
+	"backend/utils"
+
+	"gorm.io/gorm"
+)
+
+// recoveryCodeCount is the number of backup codes issued at a time
+const recoveryCodeCount = 10
+
+// TwoFactorChallengeExpiry is how long a login may wait for its second factor
+const TwoFactorChallengeExpiry = 5 * time.Minute
+
+var (
+	ErrTwoFactorNotEnrolled = errors.New("two-factor enrollment has not been started")
+	ErrTwoFactorInvalidCode = errors.New("invalid two-factor code")
+)
+
+type TwoFactorService struct {
+	issuer string
+}
+
+type TwoFactorEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"`
+}
+
+func NewTwoFactorService() *TwoFactorService {
+	return &TwoFactorService{
+		issuer: os.Getenv("TOTP_ISSUER"),
+	}
+}
+
+// IsRequired reports whether the user's role must use two-factor authentication
+func (t *TwoFactorService) IsRequired(role string) bool {
+	return os.Getenv("REQUIRE_ADMIN_2FA") == "true" && role == string(models.RoleAdmin)
+}
+
+// BeginEnrollment generates a new pending secret for the user
+func (t *TwoFactorService) BeginEnrollment(user *models.User) (*TwoFactorEnrollment, error) {
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := config.DB.Model(user).Updates(map[string]interface{}{
+		"two_factor_secret":    secret,
+		"two_factor_last_step": 0,
+	}).Error; err != nil {
+		return nil, fmt.Errorf("failed to store two-factor secret: %w", err)
+	}
+
+	return &TwoFactorEnrollment{
+		Secret:          secret,
+		ProvisioningURI: utils.TOTPProvisioningURI(secret, user.Email, t.issuer),
+	}, nil
+}
+
+// Enable confirms enrollment with a code from the authenticator app and
+// returns freshly generated recovery codes
+func (t *TwoFactorService) Enable(user *models.User, code string) ([]string, error) {
+	if user.TwoFactorSecret == nil || *user.TwoFactorSecret == "" {
+		return nil, ErrTwoFactorNotEnrolled
+	}
+
+	if !t.VerifyCode(user, code) {
+		return nil, ErrTwoFactorInvalidCode
+	}
+
+	var codes []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Update("two_factor_enabled", true).Error; err != nil {
+			return fmt.Errorf("failed to enable two-factor authentication: %w", err)
+		}
+
+		var err error
+		codes, err = t.replaceRecoveryCodes(tx, user)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Disable turns off two-factor authentication and discards recovery codes
+func (t *TwoFactorService) Disable(user *models.User) error {
+	return config.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(user).Updates(map[string]interface{}{
+			"two_factor_enabled":   false,
+			"two_factor_secret":    nil,
+			"two_factor_last_step": 0,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+		}
+
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return fmt.Errorf("failed to delete recovery codes: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// RegenerateRecoveryCodes invalidates existing recovery codes and issues new ones
+func (t *TwoFactorService) RegenerateRecoveryCodes(user *models.User) ([]string, error) {
+	var codes []string
+	err := config.DB.Transaction(func(tx *gorm.DB) error {
+		var err error
+		codes, err = t.replaceRecoveryCodes(tx, user)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// VerifyCode checks a TOTP code, refusing to accept the same time step twice
+func (t *TwoFactorService) VerifyCode(user *models.User, code string) bool {
+	if user.TwoFactorSecret == nil {
+		return false
+	}
+
+	step, ok := utils.ValidateTOTP(*user.TwoFactorSecret, code, time.Now())
+	if !ok || step <= user.TwoFactorLastStep {
+		return false
+	}
+
+	// Only the first request to claim this step wins
+	result := config.DB.Model(&models.User{}).
+		Where("id = ? AND two_factor_last_step < ?", user.ID, step).
+		Update("two_factor_last_step", step)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return false
+	}
+
+	user.TwoFactorLastStep = step
+	return true
+}
+
+// VerifyRecoveryCode checks a recovery code and consumes it on success
+func (t *TwoFactorService) VerifyRecoveryCode(user *models.User, code string) bool {
+	code = utils.NormalizeRecoveryCode(code)
+
+	var recoveryCodes []models.RecoveryCode
+	if err := config.DB.Where("user_id = ? AND used_at IS NULL", user.ID).Find(&recoveryCodes).Error; err != nil {
+		return false
+	}
+
+	for _, recoveryCode := range recoveryCodes {
+		if !utils.VerifyPassword(code, recoveryCode.CodeHash) {
+			continue
+		}
+
+		result := config.DB.Model(&models.RecoveryCode{}).
+			Where("id = ? AND used_at IS NULL", recoveryCode.ID).
+			Update("used_at", time.Now())
+		return result.Error == nil && result.RowsAffected == 1
+	}
+
+	return false
+}
+
+// RemainingRecoveryCodes counts unused recovery codes
+func (t *TwoFactorService) RemainingRecoveryCodes(user *models.User) int64 {
+	var count int64
+	config.DB.Model(&models.RecoveryCode{}).Where("user_id = ? AND used_at IS NULL", user.ID).Count(&count)
+	return count
+}
+
+// replaceRecoveryCodes deletes old recovery codes and stores hashes of new ones
+func (t *TwoFactorService) replaceRecoveryCodes(tx *gorm.DB, user *models.User) ([]string, error) {
+	codes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Where("user_id = ?", user.ID).Delete(&models.RecoveryCode{}).Error; err != nil {
+		return nil, fmt.Errorf("failed to delete recovery codes: %w", err)
+	}
+
+	for _, code := range codes {
+		hash, err := utils.HashPassword(code)
+		if err != nil {
+			return nil, err
+		}
+
+		if err := tx.Create(&models.RecoveryCode{UserID: user.ID, CodeHash: hash}).Error; err != nil {
+			return nil, fmt.Errorf("failed to store recovery code: %w", err)
+		}
+	}
+
+	return codes, nil
+}
@@ -6,6 +6,7 @@ import (
 	"crypto/sha1"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"mime/multipart"
@@ -16,6 +17,9 @@ import (
 	"time"
 )
 
+// ErrInvalidFile means an upload was rejected for its type or size
+var ErrInvalidFile = errors.New("invalid file")
+
 type CloudinaryService struct {
 	cloudName    string
 	apiKey       string
@@ -61,12 +65,12 @@ func NewCloudinaryService() *CloudinaryService {
 func (c *CloudinaryService) UploadFile(file multipart.File, filename string) (*CloudinaryUploadResponse, error) {
 	// Validate file type
 	if !c.isAllowedFileType(filename) {
-		return nil, fmt.Errorf("file type not allowed: %s", filepath.Ext(filename))
+		return nil, fmt.Errorf("%w: file type not allowed: %s", ErrInvalidFile, filepath.Ext(filename))
 	}
 
 	// Validate file size
 	if !c.isValidFileSize(file) {
-		return nil, fmt.Errorf("file size exceeds maximum allowed size")
+		return nil, fmt.Errorf("%w: file size exceeds maximum allowed size", ErrInvalidFile)
 	}
 
 	// Create form data
@@ -0,0 +1,188 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"backend/config"
+	"backend/models"
+	"backend/utils"
+)
+
+const (
+	emailVerificationExpiry = 24 * time.Hour
+	phoneVerificationExpiry = 10 * time.Minute
+	maxVerificationAttempts = 5
+)
+
+var (
+	ErrVerificationInvalid         = errors.New("invalid or expired verification code")
+	ErrVerificationTooManyAttempts = errors.New("too many verification attempts")
+	ErrAlreadyVerified             = errors.New("already verified")
+	ErrNoPhoneNumber               = errors.New("user has no phone number")
+)
+
+type VerificationService struct {
+	emailService *SendGridService
+	smsService   *TwilioService
+}
+
+func NewVerificationService() *VerificationService {
+	return &VerificationService{
+		emailService: NewSendGridService(),
+		smsService:   NewTwilioService(),
+	}
+}
+
+// EmailVerificationRequired reports whether checkout is blocked until the email is verified
+func EmailVerificationRequired() bool {
+	return os.Getenv("REQUIRE_EMAIL_VERIFICATION") == "true"
+}
+
+// PhoneVerificationRequired reports whether SMS is blocked until the phone is verified
+func PhoneVerificationRequired() bool {
+	return os.Getenv("REQUIRE_PHONE_VERIFICATION") == "true"
+}
+
+// SendEmailVerification issues a new email verification link, replacing any outstanding one
+func (v *VerificationService) SendEmailVerification(user *models.User) error {
+	if user.IsEmailVerified() {
+		return ErrAlreadyVerified
+	}
+
+	token, err := utils.GenerateSecureToken(32)
+	if err != nil {
+		return err
+	}
+
+	if err := v.storeCode(user, models.VerificationTypeEmail, user.Email, token, emailVerificationExpiry); err != nil {
+		return err
+	}
+
+	return v.emailService.SendEmailVerification(user, token)
+}
+
+// VerifyEmail consumes an email verification token and marks the address verified
+func (v *VerificationService) VerifyEmail(token string) (*models.User, error) {
+	var code models.VerificationCode
+	if err := config.DB.Preload("User").
+		Where("code_hash = ? AND type = ? AND consumed_at IS NULL AND expires_at > ?",
+			utils.HashToken(token), models.VerificationTypeEmail, time.Now()).
+		First(&code).Error; err != nil {
+		return nil, ErrVerificationInvalid
+	}
+
+	// The user may have changed their email since the link was sent
+	user := code.User
+	if code.Target != user.Email {
+		return nil, ErrVerificationInvalid
+	}
+
+	if err := v.consume(&code, &user, "email_verified_at"); err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// SendPhoneVerification texts a new verification code to the user's phone
+func (v *VerificationService) SendPhoneVerification(user *models.User) error {
+	if user.Phone == nil || *user.Phone == "" {
+		return ErrNoPhoneNumber
+	}
+
+	if user.IsPhoneVerified() {
+		return ErrAlreadyVerified
+	}
+
+	code, err := utils.GenerateNumericCode(6)
+	if err != nil {
+		return err
+	}
+
+	if err := v.storeCode(user, models.VerificationTypePhone, *user.Phone, code, phoneVerificationExpiry); err != nil {
+		return err
+	}
+
+	// Sent directly rather than via NotificationService, which refuses
+	// SMS to unverified numbers when verification is required
+	return v.smsService.SendVerificationCodeSMS(*user.Phone, code)
+}
+
+// VerifyPhone checks a phone verification code for the user
+func (v *VerificationService) VerifyPhone(user *models.User, code string) error {
+	if user.Phone == nil || *user.Phone == "" {
+		return ErrNoPhoneNumber
+	}
+
+	var pending models.VerificationCode
+	if err := config.DB.Where("user_id = ? AND type = ? AND consumed_at IS NULL AND expires_at > ?",
+		user.ID, models.VerificationTypePhone, time.Now()).
+		Order("created_at DESC").
+		First(&pending).Error; err != nil {
+		return ErrVerificationInvalid
+	}
+
+	if pending.Attempts >= maxVerificationAttempts {
+		return ErrVerificationTooManyAttempts
+	}
+
+	if pending.Target != *user.Phone {
+		return ErrVerificationInvalid
+	}
+
+	if pending.CodeHash != utils.HashToken(code) {
+		config.DB.Model(&pending).Update("attempts", pending.Attempts+1)
+		return ErrVerificationInvalid
+	}
+
+	return v.consume(&pending, user, "phone_verified_at")
+}
+
+// storeCode replaces outstanding codes of the same type with a new one
+func (v *VerificationService) storeCode(user *models.User, verificationType models.VerificationType, target, code string, expiry time.Duration) error {
+	if err := config.DB.Model(&models.VerificationCode{}).
+		Where("user_id = ? AND type = ? AND consumed_at IS NULL", user.ID, verificationType).
+		Update("consumed_at", time.Now()).Error; err != nil {
+		return fmt.Errorf("failed to invalidate previous codes: %w", err)
+	}
+
+	record := models.VerificationCode{
+		UserID:    user.ID,
+		Type:      verificationType,
+		Target:    target,
+		CodeHash:  utils.HashToken(code),
+		ExpiresAt: time.Now().Add(expiry),
+	}
+
+	if err := config.DB.Create(&record).Error; err != nil {
+		return fmt.Errorf("failed to store verification code: %w", err)
+	}
+
+	return nil
+}
+
+// consume marks the code used and stamps the matching verified-at column
+func (v *VerificationService) consume(code *models.VerificationCode, user *models.User, column string) error {
+	now := time.Now()
+
+	tx := config.DB.Begin()
+
+	if err := tx.Model(code).Update("consumed_at", now).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to consume verification code: %w", err)
+	}
+
+	if err := tx.Model(user).Update(column, now).Error; err != nil {
+		tx.Rollback()
+		return fmt.Errorf("failed to mark user verified: %w", err)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return fmt.Errorf("failed to commit verification: %w", err)
+	}
+
+	return nil
+}
@@ -1,12 +1,16 @@
 package utils
 
 import (
-	"bytes"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
+	"math/big"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,23 +19,40 @@ import (
 )
 
 type Claims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
-	Role   string `json:"role"`
+	UserID    string `json:"user_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	SessionID string `json:"session_id"`
 	jwt.RegisteredClaims
 }
 
-// GenerateJWT creates a new JWT token for a user
-func GenerateJWT(userID, email, role string) (string, error) {
+// AccessTokenExpiry returns the configured lifetime of access tokens
+func AccessTokenExpiry() time.Duration {
 	expiry, err := time.ParseDuration(os.Getenv("JWT_EXPIRY"))
 	if err != nil {
-		expiry = 24 * time.Hour // Default to 24 hours
+		expiry = 15 * time.Minute // Default to 15 minutes
 	}
+	return expiry
+}
+
+// RefreshTokenExpiry returns the configured lifetime of refresh tokens
+func RefreshTokenExpiry() time.Duration {
+	expiry, err := time.ParseDuration(os.Getenv("REFRESH_TOKEN_EXPIRY"))
+	if err != nil {
+		expiry = 30 * 24 * time.Hour // Default to 30 days
+	}
+	return expiry
+}
+
+// GenerateJWT creates a new short-lived access token bound to a session
+func GenerateJWT(userID, email, role, sessionID string) (string, error) {
+	expiry := AccessTokenExpiry()
 
 	claims := Claims{
-		UserID: userID,
-		Email:  email,
-		Role:   role,
+		UserID:    userID,
+		Email:     email,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -63,63 +84,220 @@ func ValidateJWT(tokenString string) (*Claims, error) {
 	return nil, errors.New("invalid token")
 }
 
-// HashPassword creates an Argon2id hash of the password
+// ChallengeClaims identify a user part-way through a multi-step flow, such as
+// a login waiting for its second factor. They never grant API access.
+type ChallengeClaims struct {
+	UserID  string `json:"user_id"`
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+const ChallengePurposeTwoFactor = "two_factor_login"
+
+// GenerateChallengeToken creates a short-lived token for the given purpose
+func GenerateChallengeToken(userID, purpose string, expiry time.Duration) (string, error) {
+	claims := ChallengeClaims{
+		UserID:  userID,
+		Purpose: purpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+}
+
+// ValidateChallengeToken validates a challenge token and checks its purpose
+func ValidateChallengeToken(tokenString, purpose string) (*ChallengeClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &ChallengeClaims{}, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(os.Getenv("JWT_SECRET")), nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := token.Claims.(*ChallengeClaims)
+	if !ok || !token.Valid || claims.Purpose != purpose || claims.UserID == "" {
+		return nil, errors.New("invalid challenge token")
+	}
+
+	return claims, nil
+}
+
+// GenerateSecureToken creates a random URL-safe token from n random bytes
+func GenerateSecureToken(n int) (string, error) {
+	token := make([]byte, n)
+	if _, err := rand.Read(token); err != nil {
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	return base64.RawURLEncoding.EncodeToString(token), nil
+}
+
+// GenerateRefreshToken creates a random opaque refresh token
+func GenerateRefreshToken() (string, error) {
+	return GenerateSecureToken(32)
+}
+
+// GenerateNumericCode creates a random numeric one-time code of the given length
+func GenerateNumericCode(digits int) (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < digits; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+
+	n, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate code: %w", err)
+	}
+
+	return fmt.Sprintf("%0*d", digits, n), nil
+}
+
+// HashToken returns the SHA-256 digest used to store opaque tokens
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Argon2Params are the cost parameters of an Argon2id password hash
+type Argon2Params struct {
+	Time    uint32
+	Memory  uint32 // KiB
+	Threads uint8
+	KeyLen  uint32
+}
+
+// legacyArgon2Params were hard-coded into hashes stored in the old salt:hash format
+var legacyArgon2Params = Argon2Params{Time: 1, Memory: 64 * 1024, Threads: 4, KeyLen: 32}
+
+// CurrentArgon2Params returns the configured parameters for new password hashes
+func CurrentArgon2Params() Argon2Params {
+	params := legacyArgon2Params
+
+	if t, err := strconv.ParseUint(os.Getenv("ARGON2_TIME"), 10, 32); err == nil && t > 0 {
+		params.Time = uint32(t)
+	}
+	if m, err := strconv.ParseUint(os.Getenv("ARGON2_MEMORY"), 10, 32); err == nil && m >= 8*1024 {
+		params.Memory = uint32(m)
+	}
+	if p, err := strconv.ParseUint(os.Getenv("ARGON2_THREADS"), 10, 8); err == nil && p > 0 {
+		params.Threads = uint8(p)
+	}
+
+	return params
+}
+
+// HashPassword creates an Argon2id hash of the password in the PHC string
+// format, e.g. $argon2id$v=19$m=65536,t=1,p=4$<salt>$<hash>, so the cost
+// parameters travel with the hash
 func HashPassword(password string) (string, error) {
+	params := CurrentArgon2Params()
+
 	// Generate a random salt for each user
 	salt := make([]byte, 32)
 	if _, err := rand.Read(salt); err != nil {
 		return "", fmt.Errorf("failed to generate salt: %w", err)
 	}
 
-	hash := argon2.IDKey(
-		[]byte(password),
-		salt,
-		1,       // time cost
-		64*1024, // memory cost (64MB)
-		4,       // parallelism
-		32,      // password length
-	)
+	hash := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, params.KeyLen)
 
-	// Encode both salt and hash to base64
-	encodedSalt := base64.RawStdEncoding.EncodeToString(salt)
-	encodedHash := base64.RawStdEncoding.EncodeToString(hash)
-
-	// Return salt:hash format for storage
-	return encodedSalt + ":" + encodedHash, nil
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		params.Memory,
+		params.Time,
+		params.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash),
+	), nil
 }
 
-// VerifyPassword verifies a password against its hash
+// VerifyPassword verifies a password against its hash. Both PHC strings and
+// the legacy salt:hash format are accepted.
 func VerifyPassword(password, hash string) bool {
-	// Split the stored hash to get salt and hash
-	parts := strings.Split(hash, ":")
-	if len(parts) != 2 {
+	params, salt, storedHash, err := decodePasswordHash(hash)
+	if err != nil {
 		return false
 	}
 
-	encodedSalt := parts[0]
-	encodedHash := parts[1]
+	// Compute hash with the same salt and parameters
+	computedHash := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(storedHash)))
 
-	// Decode the salt
-	salt, err := base64.RawStdEncoding.DecodeString(encodedSalt)
+	return subtle.ConstantTimeCompare(computedHash, storedHash) == 1
+}
+
+// PasswordNeedsRehash reports whether a hash uses the legacy format or
+// parameters other than the current ones, so it should be replaced the next
+// time the plaintext password is available
+func PasswordNeedsRehash(hash string) bool {
+	if !strings.HasPrefix(hash, "$argon2id$") {
+		return true
+	}
+
+	params, _, storedHash, err := decodePasswordHash(hash)
 	if err != nil {
-		return false
+		return true
 	}
 
-	// Decode the stored hash
-	storedHash, err := base64.RawStdEncoding.DecodeString(encodedHash)
+	current := CurrentArgon2Params()
+	return params.Time != current.Time ||
+		params.Memory != current.Memory ||
+		params.Threads != current.Threads ||
+		uint32(len(storedHash)) != current.KeyLen
+}
+
+// decodePasswordHash parses a PHC or legacy salt:hash encoded password hash
+func decodePasswordHash(encoded string) (Argon2Params, []byte, []byte, error) {
+	var params Argon2Params
+	var encodedSalt, encodedHash string
+
+	if strings.HasPrefix(encoded, "$") {
+		// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+		parts := strings.Split(encoded, "$")
+		if len(parts) != 6 || parts[1] != "argon2id" {
+			return params, nil, nil, errors.New("unsupported password hash")
+		}
+
+		var version int
+		if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+			return params, nil, nil, errors.New("unsupported argon2 version")
+		}
+
+		if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+			return params, nil, nil, errors.New("invalid argon2 parameters")
+		}
+
+		encodedSalt, encodedHash = parts[4], parts[5]
+	} else {
+		parts := strings.Split(encoded, ":")
+		if len(parts) != 2 {
+			return params, nil, nil, errors.New("invalid password hash")
+		}
+
+		params = legacyArgon2Params
+		encodedSalt, encodedHash = parts[0], parts[1]
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(encodedSalt)
 	if err != nil {
-		return false
+		return params, nil, nil, errors.New("invalid password salt")
 	}
 
-	// Compute hash with the same salt
-	computedHash := argon2.IDKey(
-		[]byte(password),
-		salt,
-		1,       // time cost
-		64*1024, // memory cost (64MB)
-		4,       // parallelism
-		32,      // password length
-	)
+	hash, err := base64.RawStdEncoding.DecodeString(encodedHash)
+	if err != nil || len(hash) == 0 {
+		return params, nil, nil, errors.New("invalid password hash")
+	}
+
+	if params.Time == 0 || params.Memory == 0 || params.Threads == 0 {
+		return params, nil, nil, errors.New("invalid argon2 parameters")
+	}
 
-	return bytes.Equal(computedHash, storedHash)
+	return params, salt, hash, nil
 }
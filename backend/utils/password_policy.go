@@ -0,0 +1,97 @@
+package utils
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultPasswordMinLength = 8
+	// passwordMaxLength bounds the work done hashing attacker-supplied input
+	passwordMaxLength = 128
+)
+
+//go:embed breached_passwords.txt
+var defaultBreachedPasswords string
+
+var (
+	breachedPasswords     map[string]struct{}
+	breachedPasswordsOnce sync.Once
+)
+
+// ValidatePassword enforces the password policy: a configurable minimum
+// length (PASSWORD_MIN_LENGTH), a maximum length, and rejection of common
+// breached passwords or the user's own email address
+func ValidatePassword(password, email string) error {
+	minLength := defaultPasswordMinLength
+	if n, err := strconv.Atoi(os.Getenv("PASSWORD_MIN_LENGTH")); err == nil && n > 0 {
+		minLength = n
+	}
+
+	length := len([]rune(password))
+	if length < minLength {
+		return fmt.Errorf("password must be at least %d characters long", minLength)
+	}
+	if length > passwordMaxLength {
+		return fmt.Errorf("password must be at most %d characters long", passwordMaxLength)
+	}
+
+	normalized := strings.ToLower(password)
+	if email != "" && normalized == strings.ToLower(strings.TrimSpace(email)) {
+		return fmt.Errorf("password must not be your email address")
+	}
+
+	if IsBreachedPassword(password) {
+		return fmt.Errorf("this password has appeared in a data breach; please choose a different one")
+	}
+
+	return nil
+}
+
+// IsBreachedPassword reports whether the password is on the built-in list of
+// common passwords or the file named by PASSWORD_BREACHED_LIST
+func IsBreachedPassword(password string) bool {
+	breachedPasswordsOnce.Do(loadBreachedPasswords)
+
+	_, found := breachedPasswords[strings.ToLower(password)]
+	return found
+}
+
+// loadBreachedPasswords reads the embedded list and the optional local file
+func loadBreachedPasswords() {
+	breachedPasswords = make(map[string]struct{})
+	addBreachedPasswords(bufio.NewScanner(strings.NewReader(defaultBreachedPasswords)))
+
+	path := os.Getenv("PASSWORD_BREACHED_LIST")
+	if path == "" {
+		return
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		fmt.Printf("Failed to open breached password list: %v\n", err)
+		return
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	addBreachedPasswords(scanner)
+	if err := scanner.Err(); err != nil {
+		fmt.Printf("Failed to read breached password list: %v\n", err)
+	}
+}
+
+func addBreachedPasswords(scanner *bufio.Scanner) {
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		breachedPasswords[strings.ToLower(line)] = struct{}{}
+	}
+}